@@ -0,0 +1,147 @@
+package ch912x
+
+// Capabilities describes what a product running a firmware supports,
+// the validation rejects the fields set beyond it instead of dropping them.
+type Capabilities struct {
+	UARTs           int          `json:"uarts"`
+	LinkSpeeds      []int        `json:"link_speeds,omitempty"` // Mbps
+	MinBaud         uint32       `json:"min_baud,omitempty"`    // zero when the serial is not configurable
+	MaxBaud         uint32       `json:"max_baud,omitempty"`
+	BaudRates       []uint32     `json:"baud_rates,omitempty"` // the presets of the vendor tools
+	DataBits        []int        `json:"data_bits,omitempty"`
+	StopBits        []int        `json:"stop_bits,omitempty"`
+	Parities        []UARTParity `json:"parities,omitempty"`
+	PacketSize      uint16       `json:"packet_size,omitempty"` // zero when unlimited
+	NameLength      int          `json:"name_length"`
+	DomainLength    int          `json:"domain_length,omitempty"` // zero when the client domain is not supported
+	DHCP            bool         `json:"dhcp"`
+	SerialNegotiate bool         `json:"serial_negotiate"`
+	UARTSwitch      bool         `json:"uart_switch"`    // UARTService.Enabled
+	SocketOptions   bool         `json:"socket_options"` // RandomClientPort, CloseOnLost and ClearOnReconnect
+	NTP             bool         `json:"ntp"`
+	KeepAlive       bool         `json:"keep_alive"`
+}
+
+var (
+	baudRates = []uint32{300, 600, 1200, 2400, 4800, 9600, 14400, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+	parities  = []UARTParity{ParityNone, ParityEven, ParityOdd, ParityMark, ParitySpace}
+
+	ch9120Capabilities = &Capabilities{
+		UARTs:           1,
+		LinkSpeeds:      []int{10},
+		MinBaud:         300,
+		MaxBaud:         921600,
+		BaudRates:       baudRates,
+		DataBits:        []int{5, 6, 7, 8},
+		StopBits:        []int{1, 2},
+		Parities:        parities,
+		PacketSize:      1024,
+		NameLength:      21,
+		DomainLength:    20,
+		DHCP:            true,
+		SerialNegotiate: true,
+		SocketOptions:   true,
+	}
+	ch9121Capabilities = &Capabilities{
+		UARTs:           2,
+		LinkSpeeds:      []int{10, 100},
+		MinBaud:         300,
+		MaxBaud:         921600,
+		BaudRates:       baudRates,
+		DataBits:        []int{5, 6, 7, 8},
+		StopBits:        []int{1, 2},
+		Parities:        parities,
+		PacketSize:      1024,
+		NameLength:      21,
+		DomainLength:    21,
+		DHCP:            true,
+		SerialNegotiate: true,
+		SocketOptions:   true,
+	}
+	ch9126Capabilities = &Capabilities{
+		UARTs:      1,
+		LinkSpeeds: []int{10, 100},
+		MinBaud:    300,
+		MaxBaud:    921600,
+		BaudRates:  baudRates,
+		DataBits:   []int{5, 6, 7, 8},
+		StopBits:   []int{1, 2},
+		Parities:   parities,
+		NameLength: 0x40,
+		UARTSwitch: true,
+		NTP:        true,
+		KeepAlive:  true,
+	}
+	netModuleCapabilities = &Capabilities{
+		UARTs:      1,
+		MinBaud:    300,
+		MaxBaud:    921600,
+		BaudRates:  baudRates,
+		DataBits:   []int{5, 6, 7, 8},
+		StopBits:   []int{1, 2},
+		Parities:   parities,
+		NameLength: 21,
+	}
+)
+
+// firmwareCapabilities tells the firmware of a product apart by version,
+// the versions left out, the empty one included, run the baseline.
+type firmwareCapabilities struct {
+	baseline *Capabilities
+	versions map[string]*Capabilities
+}
+
+// of returns a copy, the tables are shared by every module of the product.
+func (f *firmwareCapabilities) of(version string) *Capabilities {
+	caps, ok := f.versions[version]
+	if !ok {
+		caps = f.baseline
+	}
+	return caps.clone()
+}
+
+func (c *Capabilities) clone() *Capabilities {
+	cloned := *c
+	cloned.LinkSpeeds = append([]int(nil), c.LinkSpeeds...)
+	cloned.BaudRates = append([]uint32(nil), c.BaudRates...)
+	cloned.DataBits = append([]int(nil), c.DataBits...)
+	cloned.StopBits = append([]int(nil), c.StopBits...)
+	cloned.Parities = append([]UARTParity(nil), c.Parities...)
+	return &cloned
+}
+
+// The known firmware share the capabilities of their product so far,
+// a firmware diverging from it gets its own entry.
+var (
+	ch9120Firmware = &firmwareCapabilities{
+		baseline: ch9120Capabilities,
+		versions: map[string]*Capabilities{"2": ch9120Capabilities},
+	}
+	ch9121Firmware = &firmwareCapabilities{
+		baseline: ch9121Capabilities,
+		versions: map[string]*Capabilities{"2": ch9121Capabilities},
+	}
+	ch9126Firmware = &firmwareCapabilities{
+		baseline: ch9126Capabilities,
+		versions: map[string]*Capabilities{"V110": ch9126Capabilities},
+	}
+	netModuleFirmware = &firmwareCapabilities{
+		baseline: netModuleCapabilities,
+	}
+)
+
+func (p *CH9120) Capabilities() *Capabilities {
+	return ch9120Firmware.of(p.FirmwareVersion)
+}
+
+func (p *CH9121) Capabilities() *Capabilities {
+	return ch9121Firmware.of(p.FirmwareVersion)
+}
+
+func (p *CH9126) Capabilities() *Capabilities {
+	return ch9126Firmware.of(p.FirmwareVersion)
+}
+
+func (p *NetModule) Capabilities() *Capabilities {
+	return netModuleFirmware.of(p.Version())
+}
@@ -0,0 +1,34 @@
+package ch912x_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+func TestCapabilitiesCopied(t *testing.T) {
+	descriptor := ch912x.LookupProduct(ch912x.ProductCH9121)
+	caps := descriptor.Capabilities("2")
+	caps.UARTs = 0
+	caps.Parities[0] = ch912x.ParitySpace
+	for _, caps := range []*ch912x.Capabilities{descriptor.Capabilities("2"), descriptor.New().Capabilities()} {
+		if caps.UARTs != 2 || caps.Parities[0] != ch912x.ParityNone {
+			t.Errorf("the capabilities of the product were changed: %+v", caps)
+		}
+	}
+}
+
+func TestCapabilitiesOfVersion(t *testing.T) {
+	for _, product := range ch912x.Products() {
+		descriptor := ch912x.LookupProduct(product)
+		module := descriptor.New()
+		module.SetVersion("unknown")
+		if baseline := descriptor.Capabilities(""); !reflect.DeepEqual(module.Capabilities(), baseline) {
+			t.Errorf("%s: an unknown firmware does not run the baseline", product)
+		}
+		if _, err := ch912x.JSONSchema(product, "unknown"); err != nil {
+			t.Errorf("%s: %v", product, err)
+		}
+	}
+}
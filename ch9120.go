@@ -1,47 +1,100 @@
 package ch912x
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"io"
 	"net"
-	"strconv"
 )
 
 type CH9120 struct {
-	Kind          Kind             `json:"-"`
-	Version       string           `json:"version,omitempty"`
-	ModuleName    string           `json:"module_name,omitempty"`
-	ModuleMAC     net.HardwareAddr `json:"module_mac,omitempty"`
-	ClientMAC     net.HardwareAddr `json:"client_mac,omitempty"`
-	ModuleOptions *ModuleOptions   `json:"module_options,omitempty"`
-	UART1         *UARTService     `json:"uart_1,omitempty"`
+	PacketKind      Kind             `json:"-"`
+	FirmwareVersion string           `json:"version,omitempty"`
+	ModuleName      string           `json:"module_name,omitempty"`
+	ModuleMAC       net.HardwareAddr `json:"module_mac,omitempty"`
+	ClientMAC       net.HardwareAddr `json:"client_mac,omitempty"`
+	ModuleOptions   *ModuleOptions   `json:"module_options,omitempty"`
+	UART1           *UARTService     `json:"uart_1,omitempty"`
+	raw             []byte
 }
 
-func (p *CH9120) setClientMAC(addr net.HardwareAddr) {
-	p.ClientMAC = addr
+func (p *CH9120) Product() Product {
+	return ProductCH9120
+}
+
+func (p *CH9120) Kind() Kind {
+	return p.PacketKind
 }
 
-func (p *CH9120) identity() (Kind, net.HardwareAddr) {
-	return p.Kind, p.ModuleMAC
+func (p *CH9120) MAC() net.HardwareAddr {
+	return p.ModuleMAC
 }
 
-func (p *CH9120) moduleIP() net.IP {
+func (p *CH9120) IP() net.IP {
 	if p.ModuleOptions == nil {
 		return nil
 	}
 	return p.ModuleOptions.IP
 }
 
+func (p *CH9120) Name() string {
+	return p.ModuleName
+}
+
+func (p *CH9120) Version() string {
+	return p.FirmwareVersion
+}
+
+func (p *CH9120) Options() *ModuleOptions {
+	return p.ModuleOptions
+}
+
+func (p *CH9120) ClientAddress() net.HardwareAddr {
+	return p.ClientMAC
+}
+
+func (p *CH9120) Clone() Module {
+	cloned := *p
+	cloned.ModuleMAC = cloneBytes(p.ModuleMAC)
+	cloned.ClientMAC = cloneBytes(p.ClientMAC)
+	cloned.ModuleOptions = p.ModuleOptions.clone()
+	cloned.UART1 = p.UART1.clone()
+	cloned.raw = cloneBytes(p.raw)
+	return &cloned
+}
+
+func (p *CH9120) SetKind(kind Kind) {
+	p.PacketKind = kind
+}
+
+func (p *CH9120) SetVersion(version string) {
+	p.FirmwareVersion = version
+}
+
+func (p *CH9120) SetClientMAC(addr net.HardwareAddr) {
+	p.ClientMAC = addr
+}
+
+func (p *CH9120) Raw() []byte {
+	return p.raw
+}
+
+func (p *CH9120) SetRaw(raw []byte) {
+	p.raw = raw
+}
+
 func (p *CH9120) MarshalJSON() ([]byte, error) {
 	type Module CH9120
 	module := new(struct {
-		Product Product `json:"product"`
+		Product   Product    `json:"product"`
+		ModuleMAC macAddress `json:"module_mac,omitempty"`
+		ClientMAC macAddress `json:"client_mac,omitempty"`
 		*Module
 	})
 	module.Product = ProductCH9120
+	module.ModuleMAC = macAddress(p.ModuleMAC)
+	module.ClientMAC = macAddress(p.ClientMAC)
 	module.Module = (*Module)(p)
 	return json.Marshal(module)
 }
@@ -49,9 +102,13 @@ func (p *CH9120) MarshalJSON() ([]byte, error) {
 func (p *CH9120) UnmarshalJSON(data []byte) (err error) {
 	type Module CH9120
 	module := new(struct {
-		Product Product `json:"product"`
+		Product   Product     `json:"product"`
+		ModuleMAC *macAddress `json:"module_mac,omitempty"`
+		ClientMAC *macAddress `json:"client_mac,omitempty"`
 		*Module
 	})
+	module.ModuleMAC = (*macAddress)(&p.ModuleMAC)
+	module.ClientMAC = (*macAddress)(&p.ClientMAC)
 	module.Module = (*Module)(p)
 	err = json.Unmarshal(data, module)
 	if err == nil && module.Product != ProductCH9120 {
@@ -66,25 +123,25 @@ func (p *CH9120) ReadFrom(r io.Reader) (n int64, err error) {
 	if err != nil {
 		return
 	}
-	p.Kind = header.Kind
-	if p.Kind == KindDiscoveryResponse {
-		discovery := new(ch9121Discovery)
-		err = binary.Read(r, binary.LittleEndian, discovery)
-		p.ModuleMAC = discovery.ModuleMAC[:]
-		p.ClientMAC = discovery.ClientMAC[:]
-		p.ModuleOptions = &ModuleOptions{IP: discovery.IP[:]}
-		buf := bufio.NewReader(r)
-		moduleName, _ := buf.ReadBytes(0)
-		p.ModuleName = trimNull(moduleName)
-		version, _ := buf.ReadByte()
-		p.Version = strconv.Itoa(int(version))
+	p.PacketKind = header.Kind
+	body, err := io.ReadAll(r)
+	n = int64(binary.Size(header) + len(body))
+	if err != nil {
+		return
+	}
+	if p.PacketKind == KindDiscoveryResponse {
+		p.raw = nil
+		var ip net.IP
+		p.ModuleMAC, p.ClientMAC, ip, p.ModuleName, p.FirmwareVersion, err = readCH9121Discovery(body)
+		p.ModuleOptions = &ModuleOptions{IP: ip}
 		return
 	}
 	c := new(ch9120Configuration)
-	err = binary.Read(r, binary.LittleEndian, c)
+	err = binary.Read(bytes.NewReader(body), binary.LittleEndian, c)
 	if err != nil {
 		return
 	}
+	p.raw = body
 	p.ModuleName = trimNull(c.ModuleName[:])
 	p.ModuleMAC = c.ModuleMAC[:]
 	p.ClientMAC = c.ClientMAC[:]
@@ -118,42 +175,47 @@ func (p *CH9120) ReadFrom(r io.Reader) (n int64, err error) {
 
 func (p *CH9120) WriteTo(w io.Writer) (n int64, err error) {
 	var buf bytes.Buffer
-	h := &ch9121Header{Kind: p.Kind}
+	h := &ch9121Header{Kind: p.PacketKind}
 	copy(h.Header[:], magicCH9120)
 	_ = binary.Write(&buf, binary.LittleEndian, h)
+	if p.PacketKind == KindDiscoveryResponse {
+		writeCH9121Discovery(&buf, p.ModuleMAC, p.ClientMAC, p.IP(), p.ModuleName, p.FirmwareVersion)
+		return buf.WriteTo(w)
+	}
 	r := new(ch9120Configuration)
+	_ = binary.Read(bytes.NewReader(p.raw), binary.LittleEndian, r)
 	copy(r.ModuleMAC[:], p.ModuleMAC)
 	copy(r.ClientMAC[:], p.ClientMAC)
-	copy(r.ModuleName[:], p.ModuleName)
+	setString(r.ModuleName[:], p.ModuleName)
 	if opt := p.ModuleOptions; opt != nil {
 		copy(r.ModuleOptions.ModuleMAC[:], opt.MAC)
-		copy(r.ModuleOptions.IP[:], opt.IP)
-		copy(r.ModuleOptions.Mask[:], opt.Mask)
-		copy(r.ModuleOptions.Gateway[:], opt.Gateway)
-		r.ModuleOptions.DHCP = toVariantBool(opt.UseDHCP)
-		r.ModuleOptions.SerialNegotiate = toVariantBool(opt.SerialNegotiate)
+		copyIP(r.ModuleOptions.IP[:], opt.IP)
+		copyIP(r.ModuleOptions.Mask[:], opt.Mask)
+		copyIP(r.ModuleOptions.Gateway[:], opt.Gateway)
+		setVariantBool(&r.ModuleOptions.DHCP, opt.UseDHCP)
+		setVariantBool(&r.ModuleOptions.SerialNegotiate, opt.SerialNegotiate)
+	}
+	if uart := p.UART1; uart != nil {
+		r.UART.Mode = byte(uart.Mode)
+		setVariantBool(&r.UART.RandomClientPort, uart.RandomClientPort)
+		r.UART.ClientPort = uart.ClientPort
+		r.UART.TargetPort = uart.LocalPort
+		r.UART.Baud = uart.Baud
+		r.UART.DataBits = uart.DataBits
+		r.UART.StopBit = uart.StopBit
+		setCH9121Parity(&r.UART.Parity, uart.Parity)
+		setVariantBool(&r.UART.CloseOnLost, uart.CloseOnLost)
+		r.UART.RXSize = uart.PacketSize
+		r.UART.RXTimeout = uart.PacketTimeout
+		setVariantBool(&r.UART.ClearOnTimeout, uart.ClearOnReconnect)
+		copyIP(r.UART.ClientIP[:], uart.ClientIP)
+		setVariantBool(&r.UART.UseDomain, uart.UseDomain)
+		setString(r.UART.ClientDomain[:], uart.ClientDomain)
 	}
-	if p.UART1 != nil {
-		r.UART = ch9120UART{
-			Mode:             byte(p.UART1.Mode),
-			RandomClientPort: toVariantBool(p.UART1.RandomClientPort),
-			ClientPort:       p.UART1.ClientPort,
-			TargetPort:       p.UART1.LocalPort,
-			Baud:             p.UART1.Baud,
-			DataBits:         p.UART1.DataBits,
-			StopBit:          p.UART1.StopBit,
-			Parity:           toCH9121Parity(p.UART1.Parity),
-			CloseOnLost:      toVariantBool(p.UART1.CloseOnLost),
-			RXSize:           p.UART1.PacketSize,
-			RXTimeout:        p.UART1.PacketTimeout,
-			ClearOnTimeout:   toVariantBool(p.UART1.ClearOnReconnect),
-		}
-		copy(r.UART.ClientIP[:], p.UART1.ClientIP)
-		r.UART.UseDomain = toVariantBool(p.UART1.UseDomain)
-		copy(r.UART.ClientDomain[:], p.UART1.ClientDomain)
+	_, _ = buf.Write(encodeWithRaw(r, p.raw))
+	if buf.Len() < 285 {
+		_, _ = buf.Write(make([]byte, 285-buf.Len()))
 	}
-	_ = binary.Write(&buf, binary.LittleEndian, r)
-	_, _ = buf.Write(make([]byte, 285-buf.Len()))
 	return buf.WriteTo(w)
 }
 
@@ -176,7 +238,7 @@ type ch9120UART struct {
 	Baud             uint32
 	DataBits         byte
 	StopBit          byte
-	Parity           byte // None, Evan, Odd, Mark, Space
+	Parity           byte // Even, Odd, Mark, Space, None
 	CloseOnLost      byte
 	RXSize           uint16
 	_                [2]byte
@@ -1,7 +1,6 @@
 package ch912x
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
@@ -11,38 +10,94 @@ import (
 )
 
 type CH9121 struct {
-	Kind          Kind             `json:"-"`
-	Version       string           `json:"version,omitempty"`
-	ModuleName    string           `json:"module_name,omitempty"`
-	ModuleMAC     net.HardwareAddr `json:"module_mac,omitempty"`
-	ClientMAC     net.HardwareAddr `json:"client_mac,omitempty"`
-	ModuleOptions *ModuleOptions   `json:"module_options,omitempty"`
-	UART1         *UARTService     `json:"uart_1,omitempty"`
-	UART2         *UARTService     `json:"uart_2,omitempty"`
+	PacketKind      Kind             `json:"-"`
+	FirmwareVersion string           `json:"version,omitempty"`
+	ModuleName      string           `json:"module_name,omitempty"`
+	ModuleMAC       net.HardwareAddr `json:"module_mac,omitempty"`
+	ClientMAC       net.HardwareAddr `json:"client_mac,omitempty"`
+	ModuleOptions   *ModuleOptions   `json:"module_options,omitempty"`
+	UART1           *UARTService     `json:"uart_1,omitempty"`
+	UART2           *UARTService     `json:"uart_2,omitempty"`
+	raw             []byte
 }
 
-func (p *CH9121) setClientMAC(addr net.HardwareAddr) {
-	p.ClientMAC = addr
+func (p *CH9121) Product() Product {
+	return ProductCH9121
+}
+
+func (p *CH9121) Kind() Kind {
+	return p.PacketKind
 }
 
-func (p *CH9121) identity() (Kind, net.HardwareAddr) {
-	return p.Kind, p.ModuleMAC
+func (p *CH9121) MAC() net.HardwareAddr {
+	return p.ModuleMAC
 }
 
-func (p *CH9121) moduleIP() net.IP {
+func (p *CH9121) IP() net.IP {
 	if p.ModuleOptions == nil {
 		return nil
 	}
 	return p.ModuleOptions.IP
 }
 
+func (p *CH9121) Name() string {
+	return p.ModuleName
+}
+
+func (p *CH9121) Version() string {
+	return p.FirmwareVersion
+}
+
+func (p *CH9121) Options() *ModuleOptions {
+	return p.ModuleOptions
+}
+
+func (p *CH9121) ClientAddress() net.HardwareAddr {
+	return p.ClientMAC
+}
+
+func (p *CH9121) Clone() Module {
+	cloned := *p
+	cloned.ModuleMAC = cloneBytes(p.ModuleMAC)
+	cloned.ClientMAC = cloneBytes(p.ClientMAC)
+	cloned.ModuleOptions = p.ModuleOptions.clone()
+	cloned.UART1 = p.UART1.clone()
+	cloned.UART2 = p.UART2.clone()
+	cloned.raw = cloneBytes(p.raw)
+	return &cloned
+}
+
+func (p *CH9121) SetKind(kind Kind) {
+	p.PacketKind = kind
+}
+
+func (p *CH9121) SetVersion(version string) {
+	p.FirmwareVersion = version
+}
+
+func (p *CH9121) SetClientMAC(addr net.HardwareAddr) {
+	p.ClientMAC = addr
+}
+
+func (p *CH9121) Raw() []byte {
+	return p.raw
+}
+
+func (p *CH9121) SetRaw(raw []byte) {
+	p.raw = raw
+}
+
 func (p *CH9121) MarshalJSON() ([]byte, error) {
 	type Module CH9121
 	module := new(struct {
-		Product Product `json:"product"`
+		Product   Product    `json:"product"`
+		ModuleMAC macAddress `json:"module_mac,omitempty"`
+		ClientMAC macAddress `json:"client_mac,omitempty"`
 		*Module
 	})
 	module.Product = ProductCH9121
+	module.ModuleMAC = macAddress(p.ModuleMAC)
+	module.ClientMAC = macAddress(p.ClientMAC)
 	module.Module = (*Module)(p)
 	return json.Marshal(module)
 }
@@ -50,9 +105,13 @@ func (p *CH9121) MarshalJSON() ([]byte, error) {
 func (p *CH9121) UnmarshalJSON(data []byte) (err error) {
 	type Module CH9121
 	module := new(struct {
-		Product Product `json:"product"`
+		Product   Product     `json:"product"`
+		ModuleMAC *macAddress `json:"module_mac,omitempty"`
+		ClientMAC *macAddress `json:"client_mac,omitempty"`
 		*Module
 	})
+	module.ModuleMAC = (*macAddress)(&p.ModuleMAC)
+	module.ClientMAC = (*macAddress)(&p.ClientMAC)
 	module.Module = (*Module)(p)
 	err = json.Unmarshal(data, module)
 	if err == nil && module.Product != ProductCH9121 {
@@ -67,25 +126,25 @@ func (p *CH9121) ReadFrom(r io.Reader) (n int64, err error) {
 	if err != nil {
 		return
 	}
-	p.Kind = header.Kind
-	if p.Kind == KindDiscoveryResponse {
-		discovery := new(ch9121Discovery)
-		err = binary.Read(r, binary.LittleEndian, discovery)
-		p.ModuleMAC = discovery.ModuleMAC[:]
-		p.ClientMAC = discovery.ClientMAC[:]
-		p.ModuleOptions = &ModuleOptions{IP: discovery.IP[:]}
-		buf := bufio.NewReader(r)
-		moduleName, _ := buf.ReadBytes(0)
-		p.ModuleName = trimNull(moduleName)
-		version, _ := buf.ReadByte()
-		p.Version = strconv.Itoa(int(version))
+	p.PacketKind = header.Kind
+	body, err := io.ReadAll(r)
+	n = int64(binary.Size(header) + len(body))
+	if err != nil {
+		return
+	}
+	if p.PacketKind == KindDiscoveryResponse {
+		p.raw = nil
+		var ip net.IP
+		p.ModuleMAC, p.ClientMAC, ip, p.ModuleName, p.FirmwareVersion, err = readCH9121Discovery(body)
+		p.ModuleOptions = &ModuleOptions{IP: ip}
 		return
 	}
 	c := new(ch9121Configuration)
-	err = binary.Read(r, binary.LittleEndian, c)
+	err = binary.Read(bytes.NewReader(body), binary.LittleEndian, c)
 	if err != nil {
 		return
 	}
+	p.raw = body
 	p.ModuleName = trimNull(c.ModuleName[:])
 	p.ModuleMAC = c.ModuleMAC[:]
 	p.ClientMAC = c.ClientMAC[:]
@@ -124,52 +183,83 @@ func (p *CH9121) ReadFrom(r io.Reader) (n int64, err error) {
 
 func (p *CH9121) WriteTo(w io.Writer) (n int64, err error) {
 	var buf bytes.Buffer
-	h := &ch9121Header{Kind: p.Kind}
+	h := &ch9121Header{Kind: p.PacketKind}
 	copy(h.Header[:], magicCH9121)
 	_ = binary.Write(&buf, binary.LittleEndian, h)
+	if p.PacketKind == KindDiscoveryResponse {
+		writeCH9121Discovery(&buf, p.ModuleMAC, p.ClientMAC, p.IP(), p.ModuleName, p.FirmwareVersion)
+		return buf.WriteTo(w)
+	}
 	r := new(ch9121Configuration)
+	_ = binary.Read(bytes.NewReader(p.raw), binary.LittleEndian, r)
 	copy(r.ModuleMAC[:], p.ModuleMAC)
 	copy(r.ClientMAC[:], p.ClientMAC)
-	copy(r.ModuleName[:], p.ModuleName)
+	setString(r.ModuleName[:], p.ModuleName)
 	if opt := p.ModuleOptions; opt != nil {
 		copy(r.ModuleOptions.ModuleMAC[:], opt.MAC)
-		copy(r.ModuleOptions.IP[:], opt.IP)
-		copy(r.ModuleOptions.Mask[:], opt.Mask)
-		copy(r.ModuleOptions.Gateway[:], opt.Gateway)
-		r.ModuleOptions.DHCP = toVariantBool(opt.UseDHCP)
-		r.ModuleOptions.SerialNegotiate = toVariantBool(opt.SerialNegotiate)
-		r.EnabledUART2 = toVariantBool(opt.EnabledMinorUART)
+		copyIP(r.ModuleOptions.IP[:], opt.IP)
+		copyIP(r.ModuleOptions.Mask[:], opt.Mask)
+		copyIP(r.ModuleOptions.Gateway[:], opt.Gateway)
+		setVariantBool(&r.ModuleOptions.DHCP, opt.UseDHCP)
+		setVariantBool(&r.ModuleOptions.SerialNegotiate, opt.SerialNegotiate)
+		setVariantBool(&r.EnabledUART2, opt.EnabledMinorUART)
 	}
-	setUART := func(uart *UARTService) ch9121UART {
+	setUART := func(options *ch9121UART, uart *UARTService) {
 		if uart == nil {
-			return ch9121UART{}
-		}
-		options := ch9121UART{
-			Mode:             byte(uart.Mode),
-			RandomClientPort: toVariantBool(uart.RandomClientPort),
-			ClientPort:       uart.ClientPort,
-			TargetPort:       uart.LocalPort,
-			BaudRate:         uart.Baud,
-			DataBits:         uart.DataBits,
-			StopBit:          uart.StopBit,
-			Parity:           toCH9121Parity(uart.Parity),
-			CloseOnLost:      toVariantBool(uart.CloseOnLost),
-			RXSize:           uart.PacketSize,
-			RXTimeout:        uart.PacketTimeout,
-			ClearOnTimeout:   toVariantBool(uart.ClearOnReconnect),
-			UseDomain:        toVariantBool(uart.UseDomain),
+			return
 		}
-		copy(options.ClientIP[:], uart.ClientIP)
-		copy(options.ClientDomain[:], uart.ClientDomain)
-		return options
+		options.Mode = byte(uart.Mode)
+		setVariantBool(&options.RandomClientPort, uart.RandomClientPort)
+		options.ClientPort = uart.ClientPort
+		options.TargetPort = uart.LocalPort
+		options.BaudRate = uart.Baud
+		options.DataBits = uart.DataBits
+		options.StopBit = uart.StopBit
+		setCH9121Parity(&options.Parity, uart.Parity)
+		setVariantBool(&options.CloseOnLost, uart.CloseOnLost)
+		options.RXSize = uart.PacketSize
+		options.RXTimeout = uart.PacketTimeout
+		setVariantBool(&options.ClearOnTimeout, uart.ClearOnReconnect)
+		setVariantBool(&options.UseDomain, uart.UseDomain)
+		copyIP(options.ClientIP[:], uart.ClientIP)
+		setString(options.ClientDomain[:], uart.ClientDomain)
+	}
+	setUART(&r.UART1, p.UART1)
+	setUART(&r.UART2, p.UART2)
+	_, _ = buf.Write(encodeWithRaw(r, p.raw))
+	if buf.Len() < 285 {
+		_, _ = buf.Write(make([]byte, 285-buf.Len()))
 	}
-	r.UART1 = setUART(p.UART1)
-	r.UART2 = setUART(p.UART2)
-	_ = binary.Write(&buf, binary.LittleEndian, r)
-	_, _ = buf.Write(make([]byte, 285-buf.Len()))
 	return buf.WriteTo(w)
 }
 
+func readCH9121Discovery(data []byte) (moduleMAC, clientMAC net.HardwareAddr, ip net.IP, name, version string, err error) {
+	buf := bytes.NewBuffer(data)
+	discovery := new(ch9121Discovery)
+	err = binary.Read(buf, binary.LittleEndian, discovery)
+	moduleMAC = discovery.ModuleMAC[:]
+	clientMAC = discovery.ClientMAC[:]
+	ip = discovery.IP[:]
+	moduleName, _ := buf.ReadBytes(0)
+	name = trimNull(moduleName)
+	value, _ := buf.ReadByte()
+	version = strconv.Itoa(int(value))
+	return
+}
+
+func writeCH9121Discovery(buf *bytes.Buffer, moduleMAC, clientMAC net.HardwareAddr, ip net.IP, name, version string) {
+	discovery := new(ch9121Discovery)
+	copy(discovery.ModuleMAC[:], moduleMAC)
+	copy(discovery.ClientMAC[:], clientMAC)
+	copy(discovery.IP[:], ip.To4())
+	_ = binary.Write(buf, binary.LittleEndian, discovery)
+	_, _ = buf.WriteString(name)
+	_ = buf.WriteByte(0)
+	n, _ := strconv.Atoi(version)
+	_ = buf.WriteByte(byte(n))
+}
+
+// fromCH9121Parity reads the parity the CH9120 and the CH9121 store as Even, Odd, Mark, Space, None (0-4).
 func fromCH9121Parity(p byte) UARTParity {
 	if p == 4 {
 		return ParityNone
@@ -177,9 +267,16 @@ func fromCH9121Parity(p byte) UARTParity {
 	return UARTParity(p + 1)
 }
 
+func setCH9121Parity(dst *byte, p UARTParity) {
+	if fromCH9121Parity(*dst) != p {
+		*dst = toCH9121Parity(p)
+	}
+}
+
+// toCH9121Parity is the inverse of fromCH9121Parity.
 func toCH9121Parity(p UARTParity) byte {
 	if p == ParityNone {
-		return 0
+		return 4
 	}
 	return byte(p - 1)
 }
@@ -226,7 +323,7 @@ type ch9121UART struct {
 	BaudRate         uint32
 	DataBits         byte
 	StopBit          byte
-	Parity           byte // None, Evan, Odd, Mark, Space
+	Parity           byte // Even, Odd, Mark, Space, None
 	CloseOnLost      byte
 	RXSize           uint16
 	RXTimeout        uint16
@@ -9,38 +9,103 @@ import (
 )
 
 type CH9126 struct {
-	Kind          Kind             `json:"-"`
-	Version       string           `json:"version,omitempty"`
-	ModuleName    string           `json:"module_name,omitempty"`
-	ModuleMAC     net.HardwareAddr `json:"module_mac,omitempty"`
-	ClientMAC     net.HardwareAddr `json:"client_mac,omitempty"`
-	ModuleOptions *ModuleOptions   `json:"module_options,omitempty"`
-	UART1         *UARTService     `json:"uart_1,omitempty"`
-	NTP           *NTPService      `json:"ntp,omitempty"`
+	PacketKind      Kind             `json:"-"`
+	FirmwareVersion string           `json:"version,omitempty"`
+	ModuleName      string           `json:"module_name,omitempty"`
+	ModuleMAC       net.HardwareAddr `json:"module_mac,omitempty"`
+	ClientMAC       net.HardwareAddr `json:"client_mac,omitempty"`
+	ModuleOptions   *ModuleOptions   `json:"module_options,omitempty"`
+	UART1           *UARTService     `json:"uart_1,omitempty"`
+	NTP             *NTPService      `json:"ntp,omitempty"`
+	KeepAlive       *KeepAlive       `json:"keep_alive,omitempty"`
+	raw             []byte
 }
 
-func (p *CH9126) setClientMAC(addr net.HardwareAddr) {
-	p.ClientMAC = addr
+func (p *CH9126) Product() Product {
+	return ProductCH9126
+}
+
+func (p *CH9126) Kind() Kind {
+	return p.PacketKind
 }
 
-func (p *CH9126) identity() (Kind, net.HardwareAddr) {
-	return p.Kind, p.ModuleMAC
+func (p *CH9126) MAC() net.HardwareAddr {
+	return p.ModuleMAC
 }
 
-func (p *CH9126) moduleIP() net.IP {
+func (p *CH9126) IP() net.IP {
 	if p.ModuleOptions == nil {
 		return nil
 	}
 	return p.ModuleOptions.IP
 }
 
+func (p *CH9126) Name() string {
+	return p.ModuleName
+}
+
+func (p *CH9126) Version() string {
+	return p.FirmwareVersion
+}
+
+func (p *CH9126) Options() *ModuleOptions {
+	return p.ModuleOptions
+}
+
+func (p *CH9126) ClientAddress() net.HardwareAddr {
+	return p.ClientMAC
+}
+
+func (p *CH9126) Clone() Module {
+	cloned := *p
+	cloned.ModuleMAC = cloneBytes(p.ModuleMAC)
+	cloned.ClientMAC = cloneBytes(p.ClientMAC)
+	cloned.ModuleOptions = p.ModuleOptions.clone()
+	cloned.UART1 = p.UART1.clone()
+	if p.NTP != nil {
+		ntp := *p.NTP
+		ntp.ClientIP = cloneBytes(p.NTP.ClientIP)
+		cloned.NTP = &ntp
+	}
+	if p.KeepAlive != nil {
+		keepAlive := *p.KeepAlive
+		cloned.KeepAlive = &keepAlive
+	}
+	cloned.raw = cloneBytes(p.raw)
+	return &cloned
+}
+
+func (p *CH9126) SetKind(kind Kind) {
+	p.PacketKind = kind
+}
+
+func (p *CH9126) SetVersion(version string) {
+	p.FirmwareVersion = version
+}
+
+func (p *CH9126) SetClientMAC(addr net.HardwareAddr) {
+	p.ClientMAC = addr
+}
+
+func (p *CH9126) Raw() []byte {
+	return p.raw
+}
+
+func (p *CH9126) SetRaw(raw []byte) {
+	p.raw = raw
+}
+
 func (p *CH9126) MarshalJSON() ([]byte, error) {
 	type Module CH9126
 	module := new(struct {
-		Product Product `json:"product"`
+		Product   Product    `json:"product"`
+		ModuleMAC macAddress `json:"module_mac,omitempty"`
+		ClientMAC macAddress `json:"client_mac,omitempty"`
 		*Module
 	})
 	module.Product = ProductCH9126
+	module.ModuleMAC = macAddress(p.ModuleMAC)
+	module.ClientMAC = macAddress(p.ClientMAC)
 	module.Module = (*Module)(p)
 	return json.Marshal(module)
 }
@@ -48,9 +113,13 @@ func (p *CH9126) MarshalJSON() ([]byte, error) {
 func (p *CH9126) UnmarshalJSON(data []byte) (err error) {
 	type Module CH9126
 	module := new(struct {
-		Product Product `json:"product"`
+		Product   Product     `json:"product"`
+		ModuleMAC *macAddress `json:"module_mac,omitempty"`
+		ClientMAC *macAddress `json:"client_mac,omitempty"`
 		*Module
 	})
+	module.ModuleMAC = (*macAddress)(&p.ModuleMAC)
+	module.ClientMAC = (*macAddress)(&p.ClientMAC)
 	module.Module = (*Module)(p)
 	err = json.Unmarshal(data, module)
 	if err == nil && module.Product != ProductCH9126 {
@@ -60,21 +129,27 @@ func (p *CH9126) UnmarshalJSON(data []byte) (err error) {
 }
 
 func (p *CH9126) ReadFrom(r io.Reader) (n int64, err error) {
+	data, err := io.ReadAll(r)
+	n = int64(len(data))
+	if err != nil {
+		return
+	}
 	c := new(ch9126Configuration)
-	err = binary.Read(r, binary.LittleEndian, c)
+	err = binary.Read(bytes.NewReader(data), binary.LittleEndian, c)
 	if err != nil {
 		return
 	}
-	p.Version = string(c.Version[:])
-	p.Kind = c.Kind
+	p.raw = data
+	p.FirmwareVersion = string(c.Version[:])
+	p.PacketKind = c.Kind
 	p.ModuleName = trimNull(c.ModuleName[:])
 	p.ModuleMAC = c.ModuleMAC[:]
 	p.ClientMAC = c.ClientMAC[:]
 	p.ModuleOptions = &ModuleOptions{
-		MAC:              c.ModuleOptions.Address[:],
-		IP:               c.ModuleOptions.IP[:],
-		Gateway:          c.ModuleOptions.Gateway[:],
-		EnabledMinorUART: fromVariantBool(c.UARTService.Enabled),
+		MAC:     c.ModuleOptions.Address[:],
+		IP:      c.ModuleOptions.IP[:],
+		Mask:    c.ModuleOptions.Mask[:],
+		Gateway: c.ModuleOptions.Gateway[:],
 	}
 	p.NTP = &NTPService{
 		Enabled:     fromVariantBool(c.NTPService.Enabled),
@@ -82,9 +157,16 @@ func (p *CH9126) ReadFrom(r io.Reader) (n int64, err error) {
 		ClientIP:    c.NTPService.ClientIP[:],
 		Polling:     c.NTPService.Polling,
 		PulseOutput: fromVariantBool(c.PulseOutput),
-		KeepAlive:   fromVariantBool(c.KeepAlive.Enabled),
 	}
+	p.KeepAlive = &KeepAlive{
+		Enabled:  fromVariantBool(c.KeepAlive.Enabled),
+		Time:     c.KeepAlive.Time,
+		Interval: c.KeepAlive.Interval,
+		Probes:   c.KeepAlive.Probes,
+	}
+	enabled := fromVariantBool(c.UARTService.Enabled)
 	p.UART1 = &UARTService{
+		Enabled:       &enabled,
 		Mode:          UARTMode(c.UARTService.Mode - 1),
 		ClientIP:      c.UARTService.ClientIP[:],
 		ClientPort:    c.UARTService.ClientPort,
@@ -101,40 +183,58 @@ func (p *CH9126) ReadFrom(r io.Reader) (n int64, err error) {
 
 func (p *CH9126) WriteTo(w io.Writer) (n int64, err error) {
 	var buf bytes.Buffer
-	r := &ch9126Configuration{Kind: p.Kind}
+	r := new(ch9126Configuration)
+	_ = binary.Read(bytes.NewReader(p.raw), binary.LittleEndian, r)
+	r.Kind = p.PacketKind
 	copy(r.Header[:], magicCH9126)
-	copy(r.ModuleName[:], p.ModuleName)
+	copy(r.Version[:], p.FirmwareVersion)
+	setString(r.ModuleName[:], p.ModuleName)
 	copy(r.ModuleMAC[:], p.ModuleMAC)
 	copy(r.ClientMAC[:], p.ClientMAC)
 	if opt := p.ModuleOptions; opt != nil {
 		copy(r.ModuleOptions.Address[:], opt.MAC)
-		copy(r.ModuleOptions.IP[:], opt.IP)
-		copy(r.ModuleOptions.Mask[:], opt.Mask)
-		copy(r.ModuleOptions.Gateway[:], opt.Gateway)
-		r.UARTService.Enabled = toVariantBool(opt.EnabledMinorUART)
+		copyIP(r.ModuleOptions.IP[:], opt.IP)
+		copyIP(r.ModuleOptions.Mask[:], opt.Mask)
+		copyIP(r.ModuleOptions.Gateway[:], opt.Gateway)
+		if p.UART1 == nil || p.UART1.Enabled == nil {
+			setVariantBool(&r.UARTService.Enabled, opt.EnabledMinorUART)
+		}
 	}
 	if ntp := p.NTP; ntp != nil {
-		copy(r.NTPService.ClientIP[:], ntp.ClientIP)
-		r.NTPService.Enabled = toVariantBool(ntp.Enabled)
+		copyIP(r.NTPService.ClientIP[:], ntp.ClientIP)
+		setVariantBool(&r.NTPService.Enabled, ntp.Enabled)
 		r.NTPService.Mode = byte(ntp.Mode + 0x05)
 		r.NTPService.Polling = ntp.Polling
-		r.PulseOutput = toVariantBool(ntp.PulseOutput)
-		r.KeepAlive.Enabled = toVariantBool(ntp.KeepAlive)
+		setVariantBool(&r.PulseOutput, ntp.PulseOutput)
+		if p.KeepAlive == nil {
+			setVariantBool(&r.KeepAlive.Enabled, ntp.KeepAlive)
+		}
+	}
+	if keepAlive := p.KeepAlive; keepAlive != nil {
+		setVariantBool(&r.KeepAlive.Enabled, keepAlive.Enabled)
+		r.KeepAlive.Time = keepAlive.Time
+		r.KeepAlive.Interval = keepAlive.Interval
+		r.KeepAlive.Probes = keepAlive.Probes
 	}
 	if uart := p.UART1; uart != nil {
+		if uart.Enabled != nil {
+			setVariantBool(&r.UARTService.Enabled, *uart.Enabled)
+		}
 		r.UARTOptions.Baud = uart.Baud
 		r.UARTOptions.DataBits = uart.DataBits
 		r.UARTOptions.StopBit = uart.StopBit
 		r.UARTOptions.Parity = byte(uart.Parity)
 		r.UARTOptions.PacketSize = uart.PacketSize
 		r.UARTOptions.PacketTimeout = uart.PacketTimeout
-		r.UARTService.Mode = byte(uart.Mode)
+		r.UARTService.Mode = byte(uart.Mode + 1) // the modes start from 1, see ReadFrom
 		r.UARTService.ClientPort = uart.ClientPort
 		r.UARTService.LocalPort = uart.LocalPort
-		copy(r.UARTService.ClientIP[:], uart.ClientIP)
+		copyIP(r.UARTService.ClientIP[:], uart.ClientIP)
+	}
+	_, _ = buf.Write(encodeWithRaw(r, p.raw))
+	if buf.Len() < 367 {
+		_, _ = buf.Write(make([]byte, 367-buf.Len()))
 	}
-	_ = binary.Write(&buf, binary.LittleEndian, r)
-	_, _ = buf.Write(make([]byte, 367-buf.Len()))
 	return buf.WriteTo(w)
 }
 
@@ -150,7 +250,7 @@ type ch9126Configuration struct {
 		Baud          uint32
 		DataBits      byte
 		StopBit       byte
-		Parity        byte // Evan, Odd, Mark, Space, None
+		Parity        byte // None, Even, Odd, Mark, Space
 		PacketSize    uint16
 		PacketTimeout uint16
 		_             byte
@@ -0,0 +1,112 @@
+package ch912xsim
+
+import (
+	"net"
+	"sync"
+)
+
+type datagram struct {
+	data []byte
+	addr net.Addr
+}
+
+type announce struct {
+	sender   net.HardwareAddr
+	senderIP net.IP
+	targetIP net.IP
+}
+
+// Conn is the host side of the network, it implements ch912x.UDPTransport.
+type Conn struct {
+	network *Network
+	packets chan datagram
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (c *Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	select {
+	case <-c.done:
+		err = ErrClosed
+	case packet := <-c.packets:
+		n = copy(p, packet.data)
+		addr = packet.addr
+	}
+	return
+}
+
+func (c *Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	select {
+	case <-c.done:
+		return 0, ErrClosed
+	default:
+	}
+	if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr.Port != controlPort {
+		return len(p), nil
+	}
+	data := make([]byte, len(p))
+	copy(data, p)
+	c.network.request(data)
+	return len(p), nil
+}
+
+func (c *Conn) Close() error {
+	c.network.unlisten(c)
+	return nil
+}
+
+func (c *Conn) deliver(packet datagram) {
+	select {
+	case c.packets <- packet:
+	default:
+	}
+}
+
+func (c *Conn) shutdown() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// ARP is the host side of the link layer, it implements ch912x.ARPTransport.
+type ARP struct {
+	network *Network
+	packets chan announce
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (a *ARP) ReadARP() (sender net.HardwareAddr, senderIP, targetIP net.IP, err error) {
+	select {
+	case <-a.done:
+		err = ErrClosed
+	case packet := <-a.packets:
+		sender, senderIP, targetIP = packet.sender, packet.senderIP, packet.targetIP
+	}
+	return
+}
+
+// ProbeARP implements ch912x.ARPProber, the online devices and the hosts holding ip reply.
+func (a *ARP) ProbeARP(ip net.IP) error {
+	select {
+	case <-a.done:
+		return ErrClosed
+	default:
+	}
+	go a.network.probe(ip)
+	return nil
+}
+
+func (a *ARP) Close() error {
+	a.network.unlistenARP(a)
+	return nil
+}
+
+func (a *ARP) deliver(packet announce) {
+	select {
+	case a.packets <- packet:
+	default:
+	}
+}
+
+func (a *ARP) shutdown() {
+	a.once.Do(func() { close(a.done) })
+}
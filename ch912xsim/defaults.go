@@ -0,0 +1,77 @@
+package ch912xsim
+
+import (
+	"net"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+func factoryOptions(mac net.HardwareAddr) *ch912x.ModuleOptions {
+	return &ch912x.ModuleOptions{
+		MAC:     mac,
+		IP:      net.IPv4(192, 168, 1, 200).To4(),
+		Mask:    net.IPv4(255, 255, 255, 0).To4(),
+		Gateway: net.IPv4(192, 168, 1, 1).To4(),
+	}
+}
+
+func factoryUART(localPort uint16) *ch912x.UARTService {
+	return &ch912x.UARTService{
+		Mode:          ch912x.TCPClient,
+		ClientIP:      net.IPv4(192, 168, 1, 100).To4(),
+		ClientPort:    1000,
+		LocalPort:     localPort,
+		PacketSize:    1024,
+		PacketTimeout: 0,
+		Baud:          9600,
+		DataBits:      8,
+		StopBit:       1,
+		Parity:        ch912x.ParityNone,
+	}
+}
+
+func NewCH9120(mac net.HardwareAddr) *ch912x.CH9120 {
+	return &ch912x.CH9120{
+		FirmwareVersion: "2",
+		ModuleName:      "CH9120",
+		ModuleMAC:       mac,
+		ModuleOptions:   factoryOptions(mac),
+		UART1:           factoryUART(2000),
+	}
+}
+
+func NewCH9121(mac net.HardwareAddr) *ch912x.CH9121 {
+	return &ch912x.CH9121{
+		FirmwareVersion: "2",
+		ModuleName:      "CH9121",
+		ModuleMAC:       mac,
+		ModuleOptions:   factoryOptions(mac),
+		UART1:           factoryUART(2000),
+		UART2:           factoryUART(3000),
+	}
+}
+
+func NewCH9126(mac net.HardwareAddr) *ch912x.CH9126 {
+	enabled := true
+	uart := factoryUART(2000)
+	uart.Enabled = &enabled
+	return &ch912x.CH9126{
+		FirmwareVersion: "V110",
+		ModuleName:      "CH9126",
+		ModuleMAC:       mac,
+		ModuleOptions:   factoryOptions(mac),
+		UART1:           uart,
+		NTP: &ch912x.NTPService{
+			Enabled:  true,
+			Mode:     ch912x.NTPServer,
+			ClientIP: net.IPv4(192, 168, 1, 100).To4(),
+			Polling:  64,
+		},
+		KeepAlive: &ch912x.KeepAlive{
+			Enabled:  true,
+			Time:     7200,
+			Interval: 75,
+			Probes:   9,
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package ch912xsim
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+// Device is an emulated module, it keeps the configuration
+// between requests and reboots after the push and the reset.
+type Device struct {
+	network *Network
+	product ch912x.Product
+	mac     net.HardwareAddr
+	version string
+	mutex   sync.Mutex
+	factory ch912x.Module
+	module  ch912x.Module
+	online  bool
+	reboots int
+	timer   *time.Timer
+}
+
+func newDevice(network *Network, module ch912x.Module) (device *Device, err error) {
+	product, kind, mac, version := module.Product(), module.Kind(), module.MAC(), module.Version()
+	if ch912x.LookupProduct(product) == nil {
+		return nil, ch912x.ErrUnknownModuleType
+	} else if kind == ch912x.KindDiscoveryResponse {
+		return nil, ch912x.ErrModuleKindWrong
+	} else if mac == nil {
+		return nil, ch912x.ErrModuleMustMAC
+	}
+	device = &Device{
+		network: network,
+		product: product,
+		mac:     append(net.HardwareAddr(nil), mac...),
+		version: version,
+		online:  true,
+	}
+	if device.factory, err = clone(module); err != nil {
+		return nil, err
+	}
+	device.module, _ = clone(device.factory)
+	return
+}
+
+func (d *Device) Product() ch912x.Product {
+	return d.product
+}
+
+func (d *Device) MAC() net.HardwareAddr {
+	return d.mac
+}
+
+func (d *Device) Module() ch912x.Module {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	module, _ := clone(d.module)
+	return module
+}
+
+func (d *Device) Online() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.online
+}
+
+func (d *Device) SetOnline(online bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.online = online
+}
+
+func (d *Device) Reboots() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.reboots
+}
+
+func (d *Device) holds(ip net.IP) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.online && d.module.IP().Equal(ip)
+}
+
+func (d *Device) handle(data []byte) {
+	if !bytes.HasPrefix(data, []byte(ch912x.LookupProduct(d.product).Magic)) {
+		return
+	}
+	request := newModule(d.product)
+	if _, err := request.ReadFrom(bytes.NewReader(data)); err != nil {
+		return
+	}
+	kind, mac := request.Kind(), request.MAC()
+	clientMAC := request.ClientAddress()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !d.online {
+		return
+	}
+	if kind != ch912x.KindDiscoveryRequest && !bytes.Equal(mac, d.mac) {
+		return
+	}
+	var response ch912x.Module
+	switch kind {
+	case ch912x.KindDiscoveryRequest:
+		response = d.reply(ch912x.KindDiscoveryResponse, clientMAC)
+	case ch912x.KindPullRequest:
+		response = d.reply(ch912x.KindPullResponse, clientMAC)
+	case ch912x.KindPushRequest:
+		d.module, _ = clone(request)
+		response = d.reply(ch912x.KindPushResponse, clientMAC)
+		d.reboot()
+	case ch912x.KindResetRequest:
+		response = d.reply(ch912x.KindResetResponse, clientMAC)
+		d.module, _ = clone(d.factory)
+		d.reboot()
+	default:
+		return
+	}
+	go d.network.respond(response.IP(), response)
+}
+
+func (d *Device) reply(kind ch912x.Kind, clientMAC net.HardwareAddr) ch912x.Module {
+	module, _ := clone(d.module)
+	module.SetKind(kind)
+	module.SetVersion(d.version)
+	module.SetClientMAC(clientMAC)
+	return module
+}
+
+func (d *Device) reboot() {
+	d.online = false
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.network.RebootDelay, func() {
+		d.mutex.Lock()
+		d.online = true
+		d.reboots++
+		ip := d.module.IP()
+		d.mutex.Unlock()
+		d.network.announce(d.mac, ip)
+	})
+}
+
+func (d *Device) stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+func newModule(product ch912x.Product) ch912x.Module {
+	return ch912x.LookupProduct(product).New()
+}
+
+func clone(module ch912x.Module) (cloned ch912x.Module, err error) {
+	product, version := module.Product(), module.Version()
+	var buf bytes.Buffer
+	if _, err = module.WriteTo(&buf); err != nil {
+		return
+	}
+	cloned = newModule(product)
+	if _, err = cloned.ReadFrom(&buf); err != nil {
+		return
+	}
+	cloned.SetKind(0)
+	cloned.SetVersion(version)
+	return
+}
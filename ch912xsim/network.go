@@ -0,0 +1,244 @@
+package ch912xsim
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+const (
+	controlPort = 50000
+	queueSize   = 64
+)
+
+var (
+	ErrClosed          = errors.New("ch912xsim: network closed")
+	ErrDuplicateModule = errors.New("ch912xsim: module already attached")
+)
+
+// Network is an in-memory LAN segment with the emulated modules.
+// The modules answer every datagram written to port 50000 and
+// broadcast their responses to each endpoint listening on port 60000.
+type Network struct {
+	RebootDelay time.Duration
+	LossRate    float64
+	mutex       sync.Mutex
+	devices     map[string]*Device
+	endpoints   map[*Conn]struct{}
+	announcers  map[*ARP]struct{}
+	hosts       map[string]net.HardwareAddr
+	closed      bool
+}
+
+func New() *Network {
+	return &Network{
+		RebootDelay: 500 * time.Millisecond,
+		devices:     make(map[string]*Device),
+		endpoints:   make(map[*Conn]struct{}),
+		announcers:  make(map[*ARP]struct{}),
+		hosts:       make(map[string]net.HardwareAddr),
+	}
+}
+
+func (n *Network) Attach(module ch912x.Module) (device *Device, err error) {
+	device, err = newDevice(n, module)
+	if err != nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		return nil, ErrClosed
+	}
+	address := device.mac.String()
+	if _, ok := n.devices[address]; ok {
+		return nil, ErrDuplicateModule
+	}
+	n.devices[address] = device
+	return
+}
+
+func (n *Network) Detach(address net.HardwareAddr) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.devices, address.String())
+}
+
+func (n *Network) Device(address net.HardwareAddr) *Device {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.devices[address.String()]
+}
+
+func (n *Network) Devices() (devices []*Device) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, device := range n.devices {
+		devices = append(devices, device)
+	}
+	return
+}
+
+// AddHost places a host other than the modules on the network, it only answers the ARP probes.
+func (n *Network) AddHost(address net.HardwareAddr, ip net.IP) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.hosts[ip.String()] = append(net.HardwareAddr(nil), address...)
+}
+
+func (n *Network) RemoveHost(ip net.IP) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.hosts, ip.String())
+}
+
+func (n *Network) Listen() *Conn {
+	conn := &Conn{
+		network: n,
+		packets: make(chan datagram, queueSize),
+		done:    make(chan struct{}),
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		close(conn.done)
+	} else {
+		n.endpoints[conn] = struct{}{}
+	}
+	return conn
+}
+
+func (n *Network) ListenARP() *ARP {
+	client := &ARP{
+		network: n,
+		packets: make(chan announce, queueSize),
+		done:    make(chan struct{}),
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		close(client.done)
+	} else {
+		n.announcers[client] = struct{}{}
+	}
+	return client
+}
+
+func (n *Network) ControlPlane(clientMAC net.HardwareAddr) *ch912x.ControlPlane {
+	return ch912x.NewControlPlane(n.Listen(), n.ListenARP(), clientMAC)
+}
+
+func (n *Network) Close() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		return nil
+	}
+	n.closed = true
+	for conn := range n.endpoints {
+		conn.shutdown()
+	}
+	for client := range n.announcers {
+		client.shutdown()
+	}
+	n.endpoints = nil
+	n.announcers = nil
+	for _, device := range n.devices {
+		device.stop()
+	}
+	return nil
+}
+
+func (n *Network) request(data []byte) {
+	n.mutex.Lock()
+	devices := make([]*Device, 0, len(n.devices))
+	for _, device := range n.devices {
+		devices = append(devices, device)
+	}
+	lossRate := n.LossRate
+	n.mutex.Unlock()
+	for _, device := range devices {
+		if lossRate > 0 && rand.Float64() < lossRate {
+			continue
+		}
+		device.handle(data)
+	}
+}
+
+func (n *Network) respond(source net.IP, module ch912x.Module) {
+	var buf bytes.Buffer
+	if _, err := module.WriteTo(&buf); err != nil {
+		return
+	}
+	packet := datagram{
+		data: buf.Bytes(),
+		addr: &net.UDPAddr{IP: source, Port: controlPort},
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for conn := range n.endpoints {
+		conn.deliver(packet)
+	}
+}
+
+// HostProbe has the host probe ip the way RFC 5227 does, from the unspecified address.
+func (n *Network) HostProbe(address net.HardwareAddr, ip net.IP) {
+	n.broadcastARP(announce{sender: address, senderIP: net.IPv4zero, targetIP: ip})
+}
+
+// announce is the gratuitous ARP of the sender, it also answers the probes for ip.
+func (n *Network) announce(sender net.HardwareAddr, ip net.IP) {
+	n.broadcastARP(announce{sender: sender, senderIP: ip, targetIP: ip})
+}
+
+func (n *Network) broadcastARP(packet announce) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for client := range n.announcers {
+		client.deliver(packet)
+	}
+}
+
+func (n *Network) probe(ip net.IP) {
+	n.mutex.Lock()
+	var owners []net.HardwareAddr
+	if address, ok := n.hosts[ip.String()]; ok {
+		owners = append(owners, address)
+	}
+	devices := make([]*Device, 0, len(n.devices))
+	for _, device := range n.devices {
+		devices = append(devices, device)
+	}
+	n.mutex.Unlock()
+	for _, device := range devices {
+		if device.holds(ip) {
+			owners = append(owners, device.mac)
+		}
+	}
+	for _, address := range owners {
+		n.announce(address, ip)
+	}
+}
+
+func (n *Network) unlisten(conn *Conn) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if _, ok := n.endpoints[conn]; ok {
+		delete(n.endpoints, conn)
+		conn.shutdown()
+	}
+}
+
+func (n *Network) unlistenARP(client *ARP) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if _, ok := n.announcers[client]; ok {
+		delete(n.announcers, client)
+		client.shutdown()
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/CursedHardware/ch912x"
 	"github.com/labstack/echo/v4"
@@ -18,12 +21,23 @@ type CustomizedContext struct {
 	Address net.HardwareAddr
 }
 
+type PatchResult struct {
+	Module  ch912x.Module   `json:"module"`
+	Changes []ch912x.Change `json:"changes"`
+}
+
 func makeAPIService() http.Handler {
 	mux := echo.New()
 	mux.Use(middleware.Secure())
 	mux.GET("/discovery", onDiscovery)                      // discovery all type
+	mux.GET("/stats", onStats)                              // packet counters
+	mux.POST("/provision", onProvision)                     // assign the addresses in bulk
+	mux.GET("/products", onListProducts)                    // capabilities of all products
+	mux.GET("/products/:product", onDescribeProduct)        // capabilities of a firmware version
+	mux.GET("/schemas/:product", onProductSchema)           // JSON Schema of a firmware version
 	mux.GET("/:product/:address", onPullModule, onBind)     // pull
 	mux.POST("/:product/:address", onPushModule, onBind)    // push
+	mux.PATCH("/:product/:address", onPatchModule, onBind)  // pull, merge and push
 	mux.DELETE("/:product/:address", onResetModule, onBind) // reset
 	return mux
 }
@@ -31,12 +45,100 @@ func makeAPIService() http.Handler {
 func onDiscovery(ctx echo.Context) (err error) {
 	ctx.Response().WriteHeader(http.StatusNoContent)
 	var group errgroup.Group
-	group.Go(func() error { return plane.SendDiscovery(ch912x.ProductCH9120) })
-	group.Go(func() error { return plane.SendDiscovery(ch912x.ProductCH9121) })
-	group.Go(func() error { return plane.SendDiscovery(ch912x.ProductCH9126) })
+	for _, product := range ch912x.Products() {
+		product := product
+		group.Go(func() error { return plane.SendDiscovery(product) })
+	}
 	return group.Wait()
 }
 
+func onStats(ctx echo.Context) (err error) {
+	return ctx.JSON(http.StatusOK, plane.Stats())
+}
+
+type ProductInfo struct {
+	Product      ch912x.Product       `json:"product"`
+	Capabilities *ch912x.Capabilities `json:"capabilities"`
+}
+
+func onListProducts(ctx echo.Context) (err error) {
+	products := ch912x.Products()
+	infos := make([]*ProductInfo, len(products))
+	for i, product := range products {
+		infos[i] = &ProductInfo{
+			Product:      product,
+			Capabilities: ch912x.LookupProduct(product).Capabilities(""),
+		}
+	}
+	return ctx.JSON(http.StatusOK, infos)
+}
+
+func onDescribeProduct(ctx echo.Context) (err error) {
+	descriptor := ch912x.LookupProduct(ch912x.Product(strings.ToUpper(ctx.Param("product"))))
+	if descriptor == nil {
+		return echo.NewHTTPError(http.StatusNotFound, ch912x.ErrUnknownModuleType.Error())
+	}
+	return ctx.JSON(http.StatusOK, &ProductInfo{
+		Product:      descriptor.Product,
+		Capabilities: descriptor.Capabilities(ctx.QueryParam("version")),
+	})
+}
+
+func onProductSchema(ctx echo.Context) (err error) {
+	product := ch912x.Product(strings.ToUpper(ctx.Param("product")))
+	schema, err := ch912x.JSONSchema(product, ctx.QueryParam("version"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return
+	}
+	return ctx.Blob(http.StatusOK, "application/schema+json", data)
+}
+
+type ProvisionRequest struct {
+	Pool        string           `json:"pool"`
+	Gateway     net.IP           `json:"gateway,omitempty"`
+	Mask        net.IP           `json:"mask,omitempty"`
+	Name        string           `json:"name,omitempty"`
+	Modules     []string         `json:"modules,omitempty"`
+	Products    []ch912x.Product `json:"products,omitempty"`
+	Concurrency int              `json:"concurrency,omitempty"`
+}
+
+func onProvision(ctx echo.Context) (err error) {
+	request := new(ProvisionRequest)
+	if err = ctx.Bind(request); err != nil {
+		return
+	}
+	for i, product := range request.Products {
+		request.Products[i] = ch912x.Product(strings.ToUpper(string(product)))
+	}
+	modules := make([]net.HardwareAddr, len(request.Modules))
+	for i, module := range request.Modules {
+		if modules[i], err = ch912x.ParseMAC(module); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	results, err := plane.Provision(context.Background(), ch912x.ProvisionOptions{
+		Pool:        request.Pool,
+		Gateway:     request.Gateway,
+		Mask:        request.Mask,
+		Name:        request.Name,
+		Modules:     modules,
+		Concurrency: request.Concurrency,
+		Discover:    ch912x.DiscoverOptions{Products: request.Products},
+	})
+	switch err {
+	case nil:
+		return ctx.JSON(http.StatusOK, results)
+	case ch912x.ErrInvalidPool, ch912x.ErrPoolExhausted:
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
+
 func onPullModule(ctx echo.Context) (err error) {
 	product := ctx.(*CustomizedContext).Product
 	address := ctx.(*CustomizedContext).Address
@@ -58,19 +160,15 @@ func onResetModule(ctx echo.Context) (err error) {
 }
 
 func onPushModule(ctx echo.Context) (err error) {
+	product := ctx.(*CustomizedContext).Product
 	address := ctx.(*CustomizedContext).Address
-	var module ch912x.Module
-	switch ctx.(*CustomizedContext).Product {
-	case ch912x.ProductCH9120:
-		module = &ch912x.CH9120{Kind: ch912x.KindPushRequest, ModuleMAC: address}
-	case ch912x.ProductCH9121:
-		module = &ch912x.CH9121{Kind: ch912x.KindPushRequest, ModuleMAC: address}
-	case ch912x.ProductCH9126:
-		module = &ch912x.CH9126{Kind: ch912x.KindPushRequest, ModuleMAC: address}
-	}
+	module := ch912x.LookupProduct(product).Request(ch912x.KindPushRequest, address)
 	if err = ctx.Bind(module); err != nil {
 		return
 	}
+	if err = module.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 	module, err = plane.Push(context.Background(), module)
 	if err == nil {
 		return ctx.JSON(http.StatusOK, module)
@@ -78,28 +176,66 @@ func onPushModule(ctx echo.Context) (err error) {
 	return
 }
 
+func onPatchModule(ctx echo.Context) (err error) {
+	product := ctx.(*CustomizedContext).Product
+	address := ctx.(*CustomizedContext).Address
+	patch, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return
+	}
+	var result PatchResult
+	parsed, err := plane.Update(context.Background(), product, address, func(current ch912x.Module) (module ch912x.Module, err error) {
+		result.Module = current
+		if module, err = ch912x.MergePatch(current, patch); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if result.Changes, err = ch912x.Diff(current, module); err != nil || len(result.Changes) == 0 {
+			return nil, err
+		}
+		for _, change := range result.Changes {
+			if change.Path == "module_mac" {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "the module_mac cannot be patched")
+			}
+		}
+		if err = module.Validate(); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		result.Module = module
+		return
+	})
+	if err != nil {
+		return
+	}
+	if parsed != nil {
+		result.Module = parsed
+	}
+	return ctx.JSON(http.StatusOK, &result)
+}
+
 func onBind(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(ctx echo.Context) (err error) {
-		address, err := base64.StdEncoding.DecodeString(ctx.Param("address"))
+		address, err := ch912x.ParseMAC(ctx.Param("address"))
+		if err == nil && address == nil {
+			err = ch912x.ErrInvalidMAC
+		}
 		if err != nil {
 			err = echo.NewHTTPError(http.StatusBadRequest, err.Error())
 			return
 		}
-		product := ch912x.Product(ctx.Param("product"))
-		switch product {
-		case ch912x.ProductCH9120:
-		case ch912x.ProductCH9121:
-		case ch912x.ProductCH9126:
-		default:
+		product := ch912x.Product(strings.ToUpper(ctx.Param("product")))
+		if ch912x.LookupProduct(product) == nil {
 			err = echo.NewHTTPError(http.StatusBadRequest, ch912x.ErrUnknownModuleType)
 			return
 		}
 		err = next(&CustomizedContext{
 			Context: ctx,
 			Product: product,
-			Address: address[0:6],
+			Address: address,
 		})
-		if err != nil {
+		var conflict *ch912x.IPConflictError
+		if errors.As(err, &conflict) {
+			err = echo.NewHTTPError(http.StatusConflict, err.Error())
+		} else if err != nil {
 			if _, ok := err.(*echo.HTTPError); !ok {
 				err = echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 			}
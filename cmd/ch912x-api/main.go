@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/CursedHardware/ch912x"
+	"github.com/CursedHardware/ch912x/ch912xsim"
 	"gopkg.in/antage/eventsource.v1"
 )
 
@@ -15,12 +18,41 @@ var plane *ch912x.ControlPlane
 func init() {
 	var err error
 	var nic string
+	var useARP, useSim, useProbe bool
 	flag.StringVar(&nic, "nic", "", "")
+	flag.BoolVar(&useARP, "arp", true, "wait for the module back online via ARP (requires CAP_NET_RAW)")
+	flag.BoolVar(&useSim, "sim", false, "serve the emulated modules instead of the network interface")
+	flag.BoolVar(&useProbe, "probe", true, "probe the pushed IP for conflicts via ARP (requires -arp)")
 	flag.Parse()
-	plane, err = ch912x.ListenCH912XByName(nic)
+	if useSim {
+		plane, err = listenSimulator()
+	} else if useARP {
+		plane, err = ch912x.ListenCH912XByName(nic)
+	} else {
+		plane, err = ch912x.ListenCH912XWithoutARPByName(nic)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	plane.DetectConflict = useProbe && (useARP || useSim)
+}
+
+func listenSimulator() (*ch912x.ControlPlane, error) {
+	network := ch912xsim.New()
+	ch9120 := ch912xsim.NewCH9120(net.HardwareAddr{0x02, 0x91, 0x20, 0x00, 0x00, 0x01})
+	ch9121 := ch912xsim.NewCH9121(net.HardwareAddr{0x02, 0x91, 0x21, 0x00, 0x00, 0x01})
+	ch9126 := ch912xsim.NewCH9126(net.HardwareAddr{0x02, 0x91, 0x26, 0x00, 0x00, 0x01})
+	// the factory configurations share 192.168.1.200, keep them apart for the conflict detection
+	ch9120.ModuleOptions.IP = net.IPv4(192, 168, 1, 201)
+	ch9121.ModuleOptions.IP = net.IPv4(192, 168, 1, 202)
+	ch9126.ModuleOptions.IP = net.IPv4(192, 168, 1, 203)
+	modules := []ch912x.Module{ch9120, ch9121, ch9126}
+	for _, module := range modules {
+		if _, err := network.Attach(module); err != nil {
+			return nil, err
+		}
+	}
+	return network.ControlPlane(net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}), nil
 }
 
 func main() {
@@ -31,12 +63,14 @@ func main() {
 	mux.Handle("/events", events)
 	mux.Handle("/api/", http.StripPrefix("/api", makeAPIService()))
 	go sendDiscoveryEvents(events)
+	plane.OnPacketError(func(err *ch912x.PacketError) { log.Println(err) })
 	log.Println("listen :8080")
 	_ = http.ListenAndServe(":8080", mux)
 }
 
 func sendDiscoveryEvents(events eventsource.EventSource) {
-	for module := range plane.Discovery() {
+	subscription := plane.Subscribe(context.Background(), nil)
+	for module := range subscription.C {
 		data, _ := json.Marshal(module)
 		events.SendEventMessage(string(data), "discovery", "")
 	}
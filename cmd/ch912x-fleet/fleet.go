@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CursedHardware/ch912x"
+	"gopkg.in/yaml.v3"
+)
+
+// Fleet is the desired state keyed by the module MAC, each configuration carries its product
+// and is applied as a JSON Merge Patch onto the pulled configuration.
+type Fleet struct {
+	Modules map[string]map[string]interface{} `yaml:"modules"`
+}
+
+type Desired struct {
+	Address net.HardwareAddr
+	Product ch912x.Product
+	Config  []byte
+}
+
+type Action string
+
+const (
+	ActionInSync    Action = "in-sync"
+	ActionPush      Action = "push"
+	ActionMissing   Action = "missing"
+	ActionUnmanaged Action = "unmanaged"
+	ActionFailed    Action = "failed"
+)
+
+type Step struct {
+	Action  Action
+	Product ch912x.Product
+	Address net.HardwareAddr
+	Changes []ch912x.Change
+	Module  ch912x.Module // the push request
+	Err     error
+	config  []byte
+}
+
+// LoadFleet reads the YAML file, JSON is accepted as well being a subset of YAML.
+func LoadFleet(name string) (desired []*Desired, err error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return
+	}
+	fleet := new(Fleet)
+	if err = yaml.Unmarshal(data, fleet); err != nil {
+		return
+	}
+	for key, config := range fleet.Modules {
+		module := &Desired{}
+		if module.Address, err = net.ParseMAC(key); err != nil {
+			return nil, err
+		}
+		product, _ := config["product"].(string)
+		module.Product = ch912x.Product(strings.ToUpper(product))
+		if ch912x.LookupProduct(module.Product) == nil {
+			return nil, fmt.Errorf("%w: %s has the product %q", ch912x.ErrUnknownModuleType, key, product)
+		}
+		delete(config, "product")
+		if module.Config, err = json.Marshal(config); err != nil {
+			return nil, err
+		}
+		desired = append(desired, module)
+	}
+	sort.Slice(desired, func(i, j int) bool {
+		return desired[i].Address.String() < desired[j].Address.String()
+	})
+	return
+}
+
+// Plan discovers the modules, pulls the desired ones and compares them with the desired state.
+func Plan(ctx context.Context, plane *ch912x.ControlPlane, desired []*Desired, concurrency int) (steps []*Step, err error) {
+	discovered, err := plane.Discover(ctx, ch912x.DiscoverOptions{})
+	if err != nil {
+		return
+	}
+	seen := make(map[string]ch912x.Product)
+	for _, found := range discovered {
+		seen[found.Module.MAC().String()] = found.Module.Product()
+	}
+	steps = make([]*Step, len(desired))
+	run(len(desired), concurrency, func(i int) {
+		steps[i] = plan(ctx, plane, desired[i], seen)
+	})
+	managed := make(map[string]bool)
+	for _, module := range desired {
+		managed[module.Address.String()] = true
+	}
+	for _, found := range discovered {
+		if module := found.Module; !managed[module.MAC().String()] {
+			steps = append(steps, &Step{Action: ActionUnmanaged, Product: module.Product(), Address: module.MAC()})
+		}
+	}
+	return
+}
+
+func plan(ctx context.Context, plane *ch912x.ControlPlane, desired *Desired, seen map[string]ch912x.Product) (step *Step) {
+	step = &Step{Product: desired.Product, Address: desired.Address, config: desired.Config}
+	if product, ok := seen[desired.Address.String()]; !ok {
+		step.Action = ActionMissing
+		return
+	} else if product != desired.Product {
+		step.Action, step.Err = ActionFailed, ch912x.ErrProductMismatch
+		return
+	}
+	step.Action = ActionFailed
+	current, err := plane.Pull(ctx, desired.Product, desired.Address)
+	if err != nil {
+		step.Err = err
+		return
+	}
+	if step.Module, step.Changes, step.Err = merge(current, desired.Config); step.Err != nil {
+		return
+	}
+	step.Action = ActionInSync
+	if len(step.Changes) > 0 {
+		step.Action = ActionPush
+	}
+	return
+}
+
+func merge(current ch912x.Module, config []byte) (module ch912x.Module, changes []ch912x.Change, err error) {
+	if module, err = ch912x.MergePatch(current, config); err != nil {
+		return
+	}
+	if changes, err = ch912x.Diff(current, module); err != nil {
+		return
+	}
+	err = module.Validate()
+	return
+}
+
+// Apply pushes the drifted modules of the plan, the failed pushes are marked in place.
+// The desired state is merged again onto a fresh pull, the changes are those actually pushed.
+func Apply(ctx context.Context, plane *ch912x.ControlPlane, steps []*Step, concurrency int) {
+	run(len(steps), concurrency, func(i int) {
+		step := steps[i]
+		if step.Action != ActionPush {
+			return
+		}
+		_, err := plane.Update(ctx, step.Product, step.Address, func(current ch912x.Module) (module ch912x.Module, err error) {
+			if module, step.Changes, err = merge(current, step.config); err != nil || len(step.Changes) == 0 {
+				return nil, err
+			}
+			step.Module = module
+			return
+		})
+		if err != nil {
+			step.Action, step.Err = ActionFailed, err
+		} else if len(step.Changes) == 0 {
+			step.Action = ActionInSync
+		}
+	})
+}
+
+func run(n, concurrency int, fn func(i int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+var (
+	plane       *ch912x.ControlPlane
+	desired     []*Desired
+	command     string
+	concurrency int
+)
+
+func init() {
+	var err error
+	var nic, name string
+	var useARP bool
+	flag.StringVar(&nic, "nic", "", "")
+	flag.StringVar(&name, "file", "fleet.yaml", "the desired state keyed by MAC (YAML or JSON)")
+	flag.BoolVar(&useARP, "arp", true, "wait for the module back online via ARP (requires CAP_NET_RAW)")
+	flag.IntVar(&concurrency, "concurrency", 4, "the modules pulled and pushed at once")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] plan|apply\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if command = flag.Arg(0); command != "plan" && command != "apply" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if desired, err = LoadFleet(name); err != nil {
+		log.Fatal(err)
+	}
+	if useARP {
+		plane, err = ch912x.ListenCH912XByName(nic)
+	} else {
+		plane, err = ch912x.ListenCH912XWithoutARPByName(nic)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	plane.DetectConflict = useARP
+}
+
+func main() {
+	ctx := context.Background()
+	steps, err := Plan(ctx, plane, desired, concurrency)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if command == "apply" {
+		Apply(ctx, plane, steps, concurrency)
+	}
+	if summarize(os.Stdout, steps, command == "apply") {
+		os.Exit(1)
+	}
+}
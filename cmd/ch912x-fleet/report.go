@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+var symbols = map[Action]string{
+	ActionInSync:    "=",
+	ActionPush:      "~",
+	ActionMissing:   "?",
+	ActionUnmanaged: "+",
+	ActionFailed:    "!",
+}
+
+// summarize prints the steps followed by the counters, it reports whether any step failed.
+func summarize(w io.Writer, steps []*Step, applied bool) (failed bool) {
+	counts := make(map[Action]int)
+	for _, step := range steps {
+		counts[step.Action]++
+		fmt.Fprintf(w, "%s %s %s", symbols[step.Action], step.Product, step.Address)
+		switch {
+		case step.Err != nil:
+			fmt.Fprintf(w, ": %v\n", step.Err)
+		case step.Action == ActionPush && applied:
+			fmt.Fprintln(w, ": pushed")
+		default:
+			fmt.Fprintf(w, ": %s\n", step.Action)
+		}
+		if step.Action != ActionPush {
+			continue
+		}
+		for _, change := range step.Changes {
+			old, _ := json.Marshal(change.Old)
+			updated, _ := json.Marshal(change.New)
+			fmt.Fprintf(w, "    %s: %s -> %s\n", change.Path, old, updated)
+		}
+	}
+	verb := "to push"
+	if applied {
+		verb = "pushed"
+	}
+	fmt.Fprintf(w, "\n%d %s, %d in sync, %d missing, %d unmanaged, %d failed.\n",
+		counts[ActionPush], verb, counts[ActionInSync], counts[ActionMissing], counts[ActionUnmanaged], counts[ActionFailed])
+	return counts[ActionFailed] > 0
+}
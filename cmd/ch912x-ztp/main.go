@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+var (
+	plane    *ch912x.ControlPlane
+	mapping  *Mapping
+	interval time.Duration
+	cooldown time.Duration
+	retry    time.Duration
+)
+
+func init() {
+	var err error
+	var nic, name string
+	var useARP bool
+	flag.StringVar(&nic, "nic", "", "")
+	flag.StringVar(&name, "mapping", "mapping.json", "the desired configurations keyed by MAC, MAC range or factory fingerprint")
+	flag.BoolVar(&useARP, "arp", true, "wait for the module back online via ARP (requires CAP_NET_RAW)")
+	flag.DurationVar(&interval, "interval", 5*time.Second, "the discovery interval")
+	flag.DurationVar(&cooldown, "cooldown", time.Minute, "skip the configured module for the duration")
+	flag.DurationVar(&retry, "retry", 10*time.Second, "skip the failed module for the duration")
+	flag.Parse()
+	if mapping, err = LoadMapping(name); err != nil {
+		log.Fatal(err)
+	}
+	if useARP {
+		plane, err = ch912x.ListenCH912XByName(nic)
+	} else {
+		plane, err = ch912x.ListenCH912XWithoutARPByName(nic)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	plane.DetectConflict = useARP
+}
+
+func main() {
+	ctx := context.Background()
+	go sendDiscovery(ctx)
+	NewProvisioner(plane, mapping, cooldown, retry).Run(ctx)
+}
+
+func sendDiscovery(ctx context.Context) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, product := range ch912x.Products() {
+			if err := plane.SendDiscovery(product); err != nil {
+				log.Println(err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Provisioner pushes the assigned configuration to every discovered module drifting from it,
+// a module is handled once at a time, then skipped during the cooldown after it is in sync
+// or during the retry delay after it failed.
+type Provisioner struct {
+	plane    *ch912x.ControlPlane
+	mapping  *Mapping
+	cooldown time.Duration
+	retry    time.Duration
+	mutex    sync.Mutex
+	running  map[string]bool
+	skipped  map[string]time.Time
+}
+
+func NewProvisioner(plane *ch912x.ControlPlane, mapping *Mapping, cooldown, retry time.Duration) *Provisioner {
+	return &Provisioner{
+		plane:    plane,
+		mapping:  mapping,
+		cooldown: cooldown,
+		retry:    retry,
+		running:  make(map[string]bool),
+		skipped:  make(map[string]time.Time),
+	}
+}
+
+func (p *Provisioner) Run(ctx context.Context) {
+	subscription := p.plane.Subscribe(ctx, nil)
+	for module := range subscription.C {
+		product, address := module.Product(), module.MAC()
+		rule := p.mapping.Match(product, address, module.Name(), module.IP())
+		if rule == nil || !p.begin(address) {
+			continue
+		}
+		go func() {
+			err := p.provision(ctx, product, address, rule)
+			p.end(address, err == nil)
+			if err != nil {
+				log.Printf("%s %s (%s): %v", product, address, rule.Source, err)
+			}
+		}()
+	}
+}
+
+func (p *Provisioner) begin(address net.HardwareAddr) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	key := address.String()
+	if p.running[key] || time.Now().Before(p.skipped[key]) {
+		return false
+	}
+	p.running[key] = true
+	return true
+}
+
+func (p *Provisioner) end(address net.HardwareAddr, settled bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	key := address.String()
+	delete(p.running, key)
+	if settled {
+		p.skipped[key] = time.Now().Add(p.cooldown)
+	} else {
+		p.skipped[key] = time.Now().Add(p.retry)
+	}
+}
+
+func (p *Provisioner) provision(ctx context.Context, product ch912x.Product, address net.HardwareAddr, rule *Rule) (err error) {
+	var assigned struct {
+		Product ch912x.Product `json:"product"`
+	}
+	if err = json.Unmarshal(rule.Config, &assigned); err != nil {
+		return
+	} else if assigned.Product != "" && ch912x.Product(strings.ToUpper(string(assigned.Product))) != product {
+		return ch912x.ErrProductMismatch
+	}
+	var changes []ch912x.Change
+	_, err = p.plane.Update(ctx, product, address, func(current ch912x.Module) (module ch912x.Module, err error) {
+		if module, err = ch912x.MergePatch(current, rule.Config); err != nil {
+			return
+		}
+		if changes, err = ch912x.Diff(current, module); err != nil || len(changes) == 0 {
+			return nil, err
+		}
+		return
+	})
+	if err != nil {
+		return
+	} else if len(changes) == 0 {
+		log.Printf("%s %s (%s): in sync", product, address, rule.Source)
+		return
+	}
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	log.Printf("%s %s (%s): pushed %s", product, address, rule.Source, strings.Join(paths, ", "))
+	return
+}
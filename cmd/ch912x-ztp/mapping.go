@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+// Mapping assigns the desired configurations, the exact MAC wins over the ranges,
+// the ranges win over the factory fingerprints. The configurations are JSON Merge Patches
+// applied onto the pulled configuration, so a partial configuration keeps the rest.
+type Mapping struct {
+	Modules map[string]json.RawMessage `json:"modules"`
+	Ranges  []*MACRange                `json:"ranges"`
+	Factory []*Fingerprint             `json:"factory"`
+	modules map[string]json.RawMessage
+}
+
+type MACRange struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Config json.RawMessage `json:"config"`
+	from   net.HardwareAddr
+	to     net.HardwareAddr
+}
+
+// Fingerprint matches the discovery response of a module still in the factory configuration,
+// the empty fields match anything.
+type Fingerprint struct {
+	Product    ch912x.Product  `json:"product"`
+	ModuleName string          `json:"module_name"`
+	IP         net.IP          `json:"ip"`
+	Config     json.RawMessage `json:"config"`
+}
+
+type Rule struct {
+	Source string
+	Config json.RawMessage
+}
+
+func LoadMapping(name string) (mapping *Mapping, err error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return
+	}
+	mapping = new(Mapping)
+	if err = json.Unmarshal(data, mapping); err != nil {
+		return nil, err
+	}
+	mapping.modules = make(map[string]json.RawMessage)
+	for key, config := range mapping.Modules {
+		address, err := net.ParseMAC(key)
+		if err != nil {
+			return nil, err
+		}
+		mapping.modules[address.String()] = config
+	}
+	for _, r := range mapping.Ranges {
+		if r.from, err = net.ParseMAC(r.From); err != nil {
+			return nil, err
+		} else if r.to, err = net.ParseMAC(r.To); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range mapping.Factory {
+		f.Product = ch912x.Product(strings.ToUpper(string(f.Product)))
+	}
+	return
+}
+
+func (m *Mapping) Match(product ch912x.Product, address net.HardwareAddr, name string, ip net.IP) *Rule {
+	if config, ok := m.modules[address.String()]; ok {
+		return &Rule{Source: "module " + address.String(), Config: config}
+	}
+	for _, r := range m.Ranges {
+		if bytes.Compare(r.from, address) <= 0 && bytes.Compare(address, r.to) <= 0 {
+			return &Rule{Source: fmt.Sprintf("range %s-%s", r.from, r.to), Config: r.Config}
+		}
+	}
+	for i, f := range m.Factory {
+		if f.Product != "" && f.Product != product {
+			continue
+		} else if f.ModuleName != "" && f.ModuleName != name {
+			continue
+		} else if f.IP != nil && !f.IP.Equal(ip) {
+			continue
+		}
+		return &Rule{Source: fmt.Sprintf("factory #%d", i), Config: f.Config}
+	}
+	return nil
+}
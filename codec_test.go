@@ -0,0 +1,198 @@
+package ch912x_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+var (
+	testMAC      = net.HardwareAddr{0x02, 0x91, 0x00, 0x00, 0x00, 0x01}
+	testModes    = []ch912x.UARTMode{ch912x.TCPServer, ch912x.TCPClient, ch912x.UDPServer, ch912x.UDPClient}
+	testParities = []ch912x.UARTParity{ch912x.ParityNone, ch912x.ParityEven, ch912x.ParityOdd, ch912x.ParityMark, ch912x.ParitySpace}
+)
+
+func roundTrip(t *testing.T, module ch912x.Module) ch912x.Module {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := module.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := ch912x.LookupProduct(module.Product()).New()
+	if _, err := decoded.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestUARTRoundTrip(t *testing.T) {
+	for _, product := range []ch912x.Product{ch912x.ProductCH9120, ch912x.ProductCH9121, ch912x.ProductCH9126, ch912x.ProductNetModule} {
+		for _, mode := range testModes {
+			for _, parity := range testParities {
+				module := ch912x.LookupProduct(product).Request(ch912x.KindPushRequest, testMAC)
+				patched, err := ch912x.MergePatch(module, []byte(`{"uart_1":{"mode":"`+mode.String()+`","parity":"`+parity.String()+`"}}`))
+				if err != nil {
+					t.Fatal(err)
+				}
+				decoded := roundTrip(t, patched)
+				// the decoded module takes the place of the pulled one, pushing it back must not move anything
+				decoded = roundTrip(t, decoded)
+				changes, err := ch912x.Diff(patched, decoded)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, change := range changes {
+					if change.Path == "uart_1.mode" || change.Path == "uart_1.parity" {
+						t.Errorf("%s %s %s: %s changed from %v to %v", product, mode, parity, change.Path, change.Old, change.New)
+					}
+				}
+			}
+		}
+	}
+}
+
+// pulledPacket is the answer of a module to a pull, every byte past the magic and its terminating
+// null but the kind is filled with values the codec does not produce itself, reserved bytes included.
+func pulledPacket(t *testing.T, product ch912x.Product) (data []byte, kind int) {
+	t.Helper()
+	descriptor := ch912x.LookupProduct(product)
+	var pull, push bytes.Buffer
+	if _, err := descriptor.Request(ch912x.KindPullResponse, testMAC).WriteTo(&pull); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := descriptor.Request(ch912x.KindPushRequest, testMAC).WriteTo(&push); err != nil {
+		t.Fatal(err)
+	}
+	kind = -1
+	for i := range pull.Bytes() {
+		if pull.Bytes()[i] != push.Bytes()[i] {
+			kind = i
+		}
+	}
+	data = make([]byte, pull.Len())
+	for i := range data {
+		data[i] = byte(i*7 + 0x5a)
+	}
+	copy(data, descriptor.Magic+"\x00")
+	data[kind] = byte(ch912x.KindPullResponse)
+	if product == ch912x.ProductNetModule {
+		data[0x1d] = 55 // the length of the configuration, the vendor tool takes no other
+	}
+	return
+}
+
+func TestPullPushByteIdentical(t *testing.T) {
+	for _, product := range []ch912x.Product{ch912x.ProductCH9120, ch912x.ProductCH9121, ch912x.ProductCH9126, ch912x.ProductNetModule} {
+		pulled, kind := pulledPacket(t, product)
+		module := ch912x.LookupProduct(product).New()
+		if _, err := module.ReadFrom(bytes.NewReader(pulled)); err != nil {
+			t.Fatal(err)
+		}
+		module.SetKind(ch912x.KindPushRequest)
+		var pushed bytes.Buffer
+		if _, err := module.WriteTo(&pushed); err != nil {
+			t.Fatal(err)
+		}
+		want := append([]byte(nil), pulled...)
+		want[kind] = byte(ch912x.KindPushRequest)
+		if got := pushed.Bytes(); !bytes.Equal(got, want) {
+			for i := range want {
+				if i < len(got) && got[i] != want[i] {
+					t.Errorf("%s: byte %#x is %#02x, pulled %#02x", product, i, got[i], want[i])
+				}
+			}
+			if len(got) != len(want) {
+				t.Errorf("%s: %d bytes pushed, %d pulled", product, len(got), len(want))
+			}
+		}
+	}
+}
+
+// netModulePulled is the pull response as the NetModuleConfig tool parses it.
+func netModulePulled() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("NET_MODULE_COMM\x00")
+	buf.WriteByte(byte(ch912x.KindPullResponse))
+	buf.Write(testMAC)
+	buf.Write(clientMAC)
+	buf.WriteByte(55)
+	name := make([]byte, 21)
+	copy(name, "NET_MODULE")
+	buf.Write(name)
+	buf.WriteByte(1) // TCP Client
+	buf.Write([]byte{192, 168, 1, 200, 255, 255, 255, 0, 192, 168, 1, 1})
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(115200))
+	buf.Write([]byte{0, 8, 1}) // Odd, 8 data bits, 1 stop bit
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(5))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2000))
+	buf.Write([]byte{192, 168, 1, 100})
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1000))
+	buf.Write([]byte{3, 1}) // retries, clear the serial buffer on connect
+	buf.Write(make([]byte, 285-buf.Len()))
+	return buf.Bytes()
+}
+
+func TestNetModulePull(t *testing.T) {
+	module := new(ch912x.NetModule)
+	if _, err := module.ReadFrom(bytes.NewReader(netModulePulled())); err != nil {
+		t.Fatal(err)
+	}
+	if module.Kind() != ch912x.KindPullResponse || !bytes.Equal(module.ModuleMAC, testMAC) || !bytes.Equal(module.ClientMAC, clientMAC) {
+		t.Errorf("got the header %v %s %s", module.Kind(), module.ModuleMAC, module.ClientMAC)
+	}
+	if module.ModuleName != "NET_MODULE" || !module.IP().Equal(net.IPv4(192, 168, 1, 200)) || !module.ModuleOptions.Gateway.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Errorf("got %q %+v", module.ModuleName, module.ModuleOptions)
+	}
+	uart := module.UART1
+	if uart.Mode != ch912x.TCPClient || uart.Baud != 115200 || uart.Parity != ch912x.ParityOdd || uart.DataBits != 8 || uart.StopBit != 1 ||
+		uart.PacketTimeout != 5 || uart.LocalPort != 2000 || !uart.ClientIP.Equal(net.IPv4(192, 168, 1, 100)) || uart.ClientPort != 1000 {
+		t.Errorf("got %+v", uart)
+	}
+	if err := module.Validate(); err != nil {
+		t.Error(err)
+	}
+	module.SetKind(ch912x.KindPushRequest)
+	module.UART1.Parity = ch912x.ParityNone
+	var pushed bytes.Buffer
+	if _, err := module.WriteTo(&pushed); err != nil {
+		t.Fatal(err)
+	}
+	want := netModulePulled()
+	want[0x10] = byte(ch912x.KindPushRequest)
+	want[0x44] = 4 // None
+	if !bytes.Equal(pushed.Bytes(), want) {
+		t.Errorf("pushed %x\nwant   %x", pushed.Bytes(), want)
+	}
+}
+
+func TestNetModuleTruncated(t *testing.T) {
+	module := new(ch912x.NetModule)
+	if _, err := module.ReadFrom(bytes.NewReader(netModulePulled()[:0x40])); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestNetModuleDiscovery(t *testing.T) {
+	conn := &packetConn{packets: make(chan []byte), done: make(chan struct{})}
+	plane := ch912x.NewControlPlane(conn, nil, clientMAC)
+	defer plane.Close()
+	discovery := plane.Discovery()
+	// the response carries the name alone, shorter than the 285 bytes of the tool
+	packet := append([]byte("NET_MODULE_COMM\x00\x84"), testMAC...)
+	packet = append(packet, clientMAC...)
+	packet = append(packet, 0, 'N', 'E', 'T', 0)
+	conn.packets <- packet
+	select {
+	case module := <-discovery:
+		if module.Product() != ch912x.ProductNetModule || !bytes.Equal(module.MAC(), testMAC) || module.Name() != "NET" {
+			t.Errorf("got %s %s %q", module.Product(), module.MAC(), module.Name())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the module is not discovered")
+	}
+}
@@ -0,0 +1,66 @@
+package ch912x
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+type IPConflictError struct {
+	IP  net.IP
+	MAC net.HardwareAddr
+}
+
+func (e *IPConflictError) Error() string {
+	return fmt.Sprintf("ch912x: %s is already in use by %s", e.IP, e.MAC)
+}
+
+// ProbeIP sends ProbeCount ARP probes for ip in the style of RFC 5227,
+// any host other than the owner answering for ip or probing it meanwhile fails with *IPConflictError.
+func (p *ControlPlane) ProbeIP(ctx context.Context, ip net.IP, owner net.HardwareAddr) (err error) {
+	prober, ok := p.arpClient.(ARPProber)
+	if !ok {
+		return ErrProbeUnsupported
+	} else if ip = ip.To4(); ip == nil {
+		return ErrInvalidProbeAddress
+	}
+	claims := p.registry.watchIP(ip)
+	defer claims.release()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for probes := 0; ; {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sender := <-claims.returns:
+			if !bytes.Equal(sender, owner) {
+				return &IPConflictError{IP: ip, MAC: sender}
+			}
+		case <-timer.C:
+			if probes == p.ProbeCount {
+				return nil
+			}
+			if err = prober.ProbeARP(ip); err != nil {
+				return
+			}
+			probes++
+			timer.Reset(p.ProbeInterval)
+		}
+	}
+}
+
+func (p *ControlPlane) detectConflict(ctx context.Context, module Module) error {
+	if !p.DetectConflict {
+		return nil
+	}
+	ip := module.IP()
+	if ip == nil || ip.To4() == nil || ip.To4().Equal(net.IPv4zero) {
+		return nil
+	}
+	if options := module.Options(); options != nil && options.UseDHCP {
+		return nil
+	}
+	return p.ProbeIP(ctx, ip, module.MAC())
+}
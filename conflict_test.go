@@ -0,0 +1,66 @@
+package ch912x_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+var hostMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x99}
+
+func TestProbeIP(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 1)
+	plane.ProbeInterval = 20 * time.Millisecond
+	free, held := net.IPv4(192, 168, 1, 100), net.IPv4(192, 168, 1, 101)
+	network.AddHost(hostMAC, held)
+	// the module answering for its own address is not a conflict
+	for _, ip := range []net.IP{free, net.IPv4(192, 168, 1, 10)} {
+		if err := plane.ProbeIP(context.Background(), ip, addresses[0]); err != nil {
+			t.Errorf("%s: %v", ip, err)
+		}
+	}
+	err := plane.ProbeIP(context.Background(), held, addresses[0])
+	var conflict *ch912x.IPConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got %v, want *IPConflictError", err)
+	} else if !conflict.IP.Equal(held) || !bytes.Equal(conflict.MAC, hostMAC) {
+		t.Errorf("got %s by %s", conflict.IP, conflict.MAC)
+	}
+	if err = plane.ProbeIP(context.Background(), net.ParseIP("fe80::1"), addresses[0]); err != ch912x.ErrInvalidProbeAddress {
+		t.Errorf("got %v, want %v", err, ch912x.ErrInvalidProbeAddress)
+	}
+}
+
+// TestProbeIPSimultaneous probes the address another host is probing at the same time (RFC 5227 2.1.1).
+func TestProbeIPSimultaneous(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 1)
+	plane.ProbeInterval = 100 * time.Millisecond
+	ip := net.IPv4(192, 168, 1, 100)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		// the own probes coming back on the link are not a conflict
+		network.HostProbe(clientMAC, ip)
+		network.HostProbe(hostMAC, ip)
+	}()
+	err := plane.ProbeIP(context.Background(), ip, addresses[0])
+	var conflict *ch912x.IPConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got %v, want *IPConflictError", err)
+	} else if !bytes.Equal(conflict.MAC, hostMAC) {
+		t.Errorf("got %s, want %s", conflict.MAC, hostMAC)
+	}
+}
+
+func TestProbeIPUnsupported(t *testing.T) {
+	conn := &packetConn{packets: make(chan []byte), done: make(chan struct{})}
+	plane := ch912x.NewControlPlane(conn, nil, clientMAC)
+	defer plane.Close()
+	if err := plane.ProbeIP(context.Background(), net.IPv4(192, 168, 1, 100), testMAC); err != ch912x.ErrProbeUnsupported {
+		t.Errorf("got %v, want %v", err, ch912x.ErrProbeUnsupported)
+	}
+}
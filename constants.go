@@ -16,6 +16,7 @@ const (
 	ProductCH9120         Product    = "CH9120"
 	ProductCH9121         Product    = "CH9121"
 	ProductCH9126         Product    = "CH9126"
+	ProductNetModule      Product    = "NET_MODULE"
 	KindPushRequest       Kind       = 0x01
 	KindPullRequest       Kind       = 0x02
 	KindResetRequest      Kind       = 0x03
@@ -3,10 +3,12 @@ package ch912x
 import (
 	"bytes"
 	"context"
+	"io"
+	"math/rand"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/mdlayher/arp"
 )
 
 const (
@@ -15,14 +17,25 @@ const (
 )
 
 type ControlPlane struct {
-	udpClient   net.PacketConn
-	arpClient   *arp.Client
-	clientMAC   net.HardwareAddr
-	pairs       map[string]func(Module)
-	arpTable    map[string]func()
-	discovery   chan Module
-	Timeout     time.Duration
-	SendTimeout time.Duration
+	udpClient       UDPTransport
+	arpClient       ARPTransport
+	clientMAC       net.HardwareAddr
+	registry        *registry
+	queue           *moduleQueue
+	stats           *Stats
+	packetErrors    hookSet
+	operations      hookSet
+	discovery       *discoveryHub
+	discoveryOnce   sync.Once
+	discoveryLegacy *Subscription
+	Timeout         time.Duration
+	SendTimeout     time.Duration
+	MaxSendTimeout  time.Duration
+	Retries         int
+	RetryJitter     float64
+	DetectConflict  bool          // probe the pushed IP before Push, see ProbeIP
+	ProbeCount      int           // RFC 5227 PROBE_NUM
+	ProbeInterval   time.Duration // RFC 5227 PROBE_MIN, waits once more after the last probe
 }
 
 func ListenCH912XByName(name string) (*ControlPlane, error) {
@@ -34,158 +47,298 @@ func ListenCH912XByName(name string) (*ControlPlane, error) {
 }
 
 func ListenCH912X(ifi *net.Interface) (plane *ControlPlane, err error) {
-	if ifi == nil {
-		err = ErrInvalidNetworkInterface
+	conn, err := ListenUDP(ifi)
+	if err != nil {
+		return
+	}
+	arpClient, err := DialARP(ifi)
+	if err != nil {
+		_ = conn.Close()
 		return
 	}
-	addr := &net.UDPAddr{Port: listenPort}
-	plane = &ControlPlane{
-		discovery:   make(chan Module),
-		pairs:       make(map[string]func(Module)),
-		arpTable:    make(map[string]func()),
-		clientMAC:   ifi.HardwareAddr,
-		Timeout:     15 * time.Second,
-		SendTimeout: time.Second,
+	plane = NewControlPlane(conn, arpClient, ifi.HardwareAddr)
+	return
+}
+
+func ListenCH912XWithoutARPByName(name string) (*ControlPlane, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
 	}
-	plane.udpClient, err = net.ListenUDP("udp", addr)
-	if err == nil {
-		err = bindInterfaceToUDPConn(plane.udpClient.(*net.UDPConn), ifi)
+	return ListenCH912XWithoutARP(ifi)
+}
+
+// ListenCH912XWithoutARP opens no raw socket, Push and Reset are neither retransmitted
+// nor wait for the module back online. Linux before 5.7 still requires CAP_NET_RAW
+// for binding the socket to the interface (SO_BINDTODEVICE).
+func ListenCH912XWithoutARP(ifi *net.Interface) (plane *ControlPlane, err error) {
+	conn, err := ListenUDP(ifi)
+	if err != nil {
+		return
 	}
-	if err == nil {
-		plane.arpClient, err = arp.Dial(ifi)
+	plane = NewControlPlane(conn, nil, ifi.HardwareAddr)
+	return
+}
+
+// NewControlPlane runs the control plane over the given transports.
+// The arpClient may be nil, then Push and Reset are neither retransmitted nor wait for the module back online.
+func NewControlPlane(udpClient UDPTransport, arpClient ARPTransport, clientMAC net.HardwareAddr) *ControlPlane {
+	plane := &ControlPlane{
+		udpClient:      udpClient,
+		arpClient:      arpClient,
+		clientMAC:      clientMAC,
+		discovery:      newDiscoveryHub(),
+		registry:       newRegistry(),
+		queue:          newModuleQueue(),
+		stats:          new(Stats),
+		Timeout:        15 * time.Second,
+		SendTimeout:    time.Second,
+		MaxSendTimeout: 4 * time.Second,
+		Retries:        3,
+		RetryJitter:    0.2,
+		ProbeCount:     3,
+		ProbeInterval:  time.Second,
 	}
-	if err == nil {
-		go plane.watchUDP()
+	go plane.watchUDP()
+	if arpClient != nil {
 		go plane.watchARP()
 	}
-	return
+	return plane
 }
 
 func (p *ControlPlane) watchUDP() {
 	var data [0x200]byte
 	for {
-		n, _, err := p.udpClient.ReadFrom(data[:])
+		n, addr, err := p.udpClient.ReadFrom(data[:])
 		if err != nil {
 			break
 		}
-		go p.handleResponse(data[:n])
+		packet := make([]byte, n)
+		copy(packet, data[:n])
+		go p.handleResponse(addr, packet)
 	}
-	close(p.discovery)
+	p.discovery.close()
 }
 
 func (p *ControlPlane) watchARP() {
 	for {
-		packet, _, err := p.arpClient.Read()
+		sender, senderIP, targetIP, err := p.arpClient.ReadARP()
 		if err != nil {
 			break
 		}
-		go p.handleARP(packet)
+		go p.handleARP(sender, senderIP, targetIP)
 	}
 	return
 }
 
-func (p *ControlPlane) Discovery() <-chan Module {
-	return p.discovery
-}
-
 func (p *ControlPlane) SendDiscovery(product Product) (err error) {
-	err = ErrUnknownModuleType
-	switch product {
-	case ProductCH9120:
-		err = p.push(&CH9120{Kind: KindDiscoveryRequest})
-	case ProductCH9121:
-		err = p.push(&CH9121{Kind: KindDiscoveryRequest})
-	case ProductCH9126:
-		err = p.push(&CH9126{Kind: KindDiscoveryRequest})
+	descriptor := LookupProduct(product)
+	if descriptor == nil {
+		return ErrUnknownModuleType
 	}
-	return
+	return p.push(descriptor.Request(KindDiscoveryRequest, nil))
 }
 
 func (p *ControlPlane) Pull(ctx context.Context, product Product, address net.HardwareAddr) (module Module, err error) {
-	err = ErrUnknownModuleType
-	switch product {
-	case ProductCH9120:
-		module, err = p.send(ctx, &CH9120{Kind: KindPullRequest, ModuleMAC: address})
-	case ProductCH9121:
-		module, err = p.send(ctx, &CH9121{Kind: KindPullRequest, ModuleMAC: address})
-	case ProductCH9126:
-		module, err = p.send(ctx, &CH9126{Kind: KindPullRequest, ModuleMAC: address})
+	release, err := p.queue.acquire(ctx, address)
+	if err != nil {
+		return
 	}
-	return
+	defer release()
+	return p.pull(ctx, product, address)
+}
+
+func (p *ControlPlane) pull(ctx context.Context, product Product, address net.HardwareAddr) (module Module, err error) {
+	descriptor := LookupProduct(product)
+	if descriptor == nil {
+		err = ErrUnknownModuleType
+		return
+	}
+	return p.send(ctx, descriptor.Request(KindPullRequest, address))
 }
 
+// Push applies the module and waits for it to come back online,
+// parsed is nil when the response is lost but the module is seen coming back.
 func (p *ControlPlane) Push(ctx context.Context, module Module) (parsed Module, err error) {
-	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
-	defer cancel()
-	kind, address := module.identity()
-	if kind != KindPushRequest {
-		err = ErrModuleKindWrong
+	if err = checkPushRequest(module); err != nil {
+		return
+	}
+	address := module.MAC()
+	release, err := p.queue.acquire(ctx, address)
+	if err != nil {
+		return
+	}
+	defer release()
+	if err = p.detectConflict(ctx, module); err != nil {
+		return
+	}
+	parsed, _, err = p.apply(ctx, module)
+	return
+}
+
+// Update pulls the module, passes it to fn and pushes what fn returns without letting another
+// operation on the module run in between, fn returns nil to push nothing.
+func (p *ControlPlane) Update(ctx context.Context, product Product, address net.HardwareAddr, fn func(current Module) (Module, error)) (parsed Module, err error) {
+	release, err := p.queue.acquire(ctx, address)
+	if err != nil {
 		return
 	}
-	parsed, err = p.send(ctx, module)
+	defer release()
+	current, err := p.pull(ctx, product, address)
 	if err != nil {
 		return
 	}
-	select {
-	case <-ctx.Done():
+	module, err := fn(current)
+	if err != nil || module == nil {
+		return
+	}
+	if err = checkPushRequest(module); err != nil {
 		return
-	case <-p.waitFirstARP(address):
+	} else if !bytes.Equal(module.MAC(), address) {
+		err = ErrModuleMACChanged
 		return
 	}
+	if err = p.detectConflict(ctx, module); err != nil {
+		return
+	}
+	parsed, _, err = p.apply(ctx, module)
+	return
 }
 
-func (p *ControlPlane) Reset(ctx context.Context, product Product, address net.HardwareAddr) (module Module, err error) {
+// apply broadcasts the push addressed by MAC, the module may be moving to another address,
+// and waits up to Timeout for the module to come back online.
+func (p *ControlPlane) apply(ctx context.Context, module Module) (parsed Module, offline bool, err error) {
 	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
 	defer cancel()
-	err = ErrUnknownModuleType
-	switch product {
-	case ProductCH9120:
-		module, err = p.send(ctx, &CH9120{Kind: KindResetRequest, ModuleMAC: address})
-	case ProductCH9121:
-		module, err = p.send(ctx, &CH9121{Kind: KindResetRequest, ModuleMAC: address})
-	case ProductCH9126:
-		module, err = p.send(ctx, &CH9126{Kind: KindResetRequest, ModuleMAC: address})
+	return p.sendAndWaitOnline(ctx, module, nil)
+}
+
+func checkPushRequest(module Module) error {
+	kind, address := module.Kind(), module.MAC()
+	if kind != KindPushRequest {
+		return ErrModuleKindWrong
+	} else if address == nil {
+		return ErrModuleMustMAC
 	}
-	if err != nil {
+	return module.Validate()
+}
+
+func (p *ControlPlane) Reset(ctx context.Context, product Product, address net.HardwareAddr) (module Module, err error) {
+	descriptor := LookupProduct(product)
+	if descriptor == nil {
+		err = ErrUnknownModuleType
 		return
 	}
-	select {
-	case <-ctx.Done():
+	release, err := p.queue.acquire(ctx, address)
+	if err != nil {
 		return
-	case <-p.waitFirstARP(address):
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	module, _, err = p.sendAndWaitOnline(ctx, descriptor.Request(KindResetRequest, address), nil)
+	return
+}
+
+// sendAndWaitOnline sends the request to ip, or broadcasts it when ip is nil.
+// The module not seen back online before ctx is done is reported as offline, not as an error.
+func (p *ControlPlane) sendAndWaitOnline(ctx context.Context, module Module, ip net.IP) (parsed Module, offline bool, err error) {
+	var online *pendingARP
+	if address := module.MAC(); p.arpClient != nil && address != nil {
+		online = p.registry.watchARP(address)
+		defer online.release()
+	}
+	parsed, err = p.sendTo(ctx, module, ip, online)
+	if err != nil || online == nil {
 		return
 	}
+	if err = online.wait(ctx); err == context.DeadlineExceeded {
+		offline, err = true, nil
+	}
+	return
+}
+
+// QueueDepth returns the number of the operations against the module, including the running one.
+func (p *ControlPlane) QueueDepth(address net.HardwareAddr) int {
+	return p.queue.depth(address)
 }
 
 func (p *ControlPlane) send(ctx context.Context, module Module) (parsed Module, err error) {
-	ctx, cancel := context.WithTimeout(ctx, p.SendTimeout)
-	defer cancel()
-	module.setClientMAC(p.clientMAC)
-	_, addr := module.identity()
+	return p.sendTo(ctx, module, module.IP(), nil)
+}
+
+// sendTo retransmits the request up to Retries times, each attempt waits for the response
+// from SendTimeout doubling up to MaxSendTimeout, randomized by RetryJitter.
+// The push and the reset are applied on receipt and reboot the module, they are only retransmitted
+// while watching the module come back online, until it does: parsed is nil when only the announcement is seen.
+func (p *ControlPlane) sendTo(ctx context.Context, module Module, ip net.IP, online *pendingARP) (parsed Module, err error) {
+	module.SetClientMAC(p.clientMAC)
+	kind, addr := module.Kind(), module.MAC()
 	if addr == nil {
 		err = ErrModuleMustMAC
 		return
-	} else if _, ok := p.pairs[addr.String()]; ok {
-		err = ErrTaskRunning
-		return
 	}
-	returns := make(chan Module, 1)
-	p.pairs[addr.String()] = func(parsed Module) { returns <- parsed }
-	defer delete(p.pairs, addr.String())
-	if err = p.push(module); err != nil {
+	pending, err := p.registry.register(kind, addr)
+	if err != nil {
 		return
 	}
-	select {
-	case <-ctx.Done():
-		err = ctx.Err()
-	case parsed = <-returns:
-		err, _ = parsed.(error)
+	defer pending.release()
+	var announced <-chan struct{}
+	if online != nil {
+		announced = online.done
 	}
-	return
+	reboots := kind == KindPushRequest || kind == KindResetRequest
+	operation := &Operation{Kind: kind, Address: addr}
+	started := time.Now()
+	defer func() {
+		operation.Duration = time.Since(started)
+		operation.Err = err
+		p.reportOperation(operation)
+	}()
+	for {
+		operation.Attempts++
+		if err = p.pushTo(module, ip); err != nil {
+			return
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, p.backoff(operation.Attempts))
+		select {
+		case parsed = <-pending.returns:
+		case <-announced:
+		case <-attemptCtx.Done():
+			// the response or the announcement arriving as the attempt times out is not retransmitted over
+			select {
+			case parsed = <-pending.returns:
+			case <-announced:
+			default:
+				err = attemptCtx.Err()
+			}
+		}
+		cancel()
+		if err == nil || ctx.Err() != nil || operation.Attempts > p.Retries || (reboots && online == nil) {
+			return
+		}
+	}
+}
+
+func (p *ControlPlane) backoff(attempt int) time.Duration {
+	timeout := p.SendTimeout
+	for i := 1; i < attempt && (p.MaxSendTimeout <= 0 || timeout < p.MaxSendTimeout); i++ {
+		timeout *= 2
+	}
+	if p.MaxSendTimeout > 0 && timeout > p.MaxSendTimeout {
+		timeout = p.MaxSendTimeout
+	}
+	if p.RetryJitter > 0 {
+		timeout += time.Duration((rand.Float64()*2 - 1) * p.RetryJitter * float64(timeout))
+	}
+	return timeout
 }
 
 func (p *ControlPlane) push(module Module) (err error) {
-	ip := module.moduleIP()
+	return p.pushTo(module, module.IP())
+}
+
+func (p *ControlPlane) pushTo(module Module, ip net.IP) (err error) {
 	if ip == nil {
 		ip = net.IPv4bcast
 	}
@@ -195,46 +348,48 @@ func (p *ControlPlane) push(module Module) (err error) {
 	return
 }
 
-func (p *ControlPlane) waitFirstARP(address net.HardwareAddr) chan struct{} {
-	returns := make(chan struct{}, 1)
-	p.arpTable[address.String()] = func() { returns <- struct{}{} }
-	defer delete(p.arpTable, address.String())
-	return returns
-}
-
-func (p *ControlPlane) handleResponse(data []byte) {
-	var module Module
-	switch {
-	case bytes.HasPrefix(data, []byte(magicCH9120)):
-		module = new(CH9120)
-	case bytes.HasPrefix(data, []byte(magicCH9121)):
-		module = new(CH9121)
-	case bytes.HasPrefix(data, []byte(magicCH9126)):
-		module = new(CH9126)
-	case bytes.HasPrefix(data, []byte(magicModule)):
+func (p *ControlPlane) handleResponse(addr net.Addr, data []byte) {
+	atomic.AddUint64(&p.stats.Received, 1)
+	descriptor := lookupMagic(data)
+	if descriptor == nil {
+		p.dropPacket(addr, data, ErrUnknownModuleType)
 		return
-	default:
-		panic(ErrUnknownModuleType)
 	}
-	_, _ = module.ReadFrom(bytes.NewReader(data))
-	kind, addr := module.identity()
-	if kind == KindDiscoveryResponse {
-		p.discovery <- module
-	} else if fn, ok := p.pairs[addr.String()]; ok {
-		fn(module)
+	module := descriptor.New()
+	if _, err := module.ReadFrom(bytes.NewReader(data)); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrTruncatedPacket
+		}
+		p.dropPacket(addr, data, err)
+		return
 	}
-	return
+	kind, address := module.Kind(), module.MAC()
+	switch kind {
+	case KindDiscoveryResponse:
+		p.discovery.publish(module)
+	case KindPushResponse, KindPullResponse, KindResetResponse:
+		p.registry.resolve(kind, address, module)
+	default:
+		p.dropPacket(addr, data, ErrModuleKindWrong)
+		return
+	}
+	atomic.AddUint64(&p.stats.Handled, 1)
 }
 
-func (p *ControlPlane) handleARP(packet *arp.Packet) {
-	if fn, ok := p.arpTable[packet.SenderHardwareAddr.String()]; ok {
-		fn()
+// handleARP takes the probe of another host for the same address as a claim too (RFC 5227 2.1.1),
+// the probes sent by ProbeIP come back on the link and are left out.
+func (p *ControlPlane) handleARP(sender net.HardwareAddr, senderIP, targetIP net.IP) {
+	p.registry.announce(sender)
+	if !senderIP.Equal(net.IPv4zero) {
+		p.registry.claim(sender, senderIP)
+	} else if !bytes.Equal(sender, p.clientMAC) {
+		p.registry.claim(sender, targetIP)
 	}
 }
 
 func (p *ControlPlane) Close() (err error) {
 	err = p.udpClient.Close()
-	if err == nil {
+	if err == nil && p.arpClient != nil {
 		err = p.arpClient.Close()
 	}
 	return
@@ -0,0 +1,256 @@
+package ch912x_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+	"github.com/CursedHardware/ch912x/ch912xsim"
+)
+
+var clientMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+
+// newNetwork attaches count CH9121 modules at 192.168.1.10 onwards, the control plane
+// retransmits several times while a module reboots, the module ignores them until it is back.
+func newNetwork(t *testing.T, count int) (network *ch912xsim.Network, plane *ch912x.ControlPlane, addresses []net.HardwareAddr) {
+	t.Helper()
+	network = ch912xsim.New()
+	network.RebootDelay = 500 * time.Millisecond
+	for i := 0; i < count; i++ {
+		module := ch912xsim.NewCH9121(net.HardwareAddr{0x02, 0x91, 0x21, 0x00, byte(i >> 8), byte(i)})
+		module.ModuleOptions.IP = net.IPv4(192, 168, 1, byte(10+i)).To4()
+		if _, err := network.Attach(module); err != nil {
+			t.Fatal(err)
+		}
+		addresses = append(addresses, module.ModuleMAC)
+	}
+	plane = network.ControlPlane(clientMAC)
+	configure(plane)
+	t.Cleanup(func() {
+		_ = plane.Close()
+		_ = network.Close()
+	})
+	return
+}
+
+func configure(plane *ch912x.ControlPlane) {
+	plane.Timeout = 5 * time.Second
+	plane.SendTimeout = 20 * time.Millisecond
+	plane.MaxSendTimeout = 40 * time.Millisecond
+	plane.Retries = 20
+}
+
+func rename(t *testing.T, plane *ch912x.ControlPlane, address net.HardwareAddr, name string) {
+	current, err := plane.Pull(context.Background(), ch912x.ProductCH9121, address)
+	if err != nil {
+		t.Errorf("pull %s: %v", address, err)
+		return
+	}
+	module, err := ch912x.MergePatch(current, []byte(`{"module_name":"`+name+`"}`))
+	if err != nil {
+		t.Errorf("merge %s: %v", address, err)
+		return
+	}
+	if _, err = plane.Push(context.Background(), module); err != nil {
+		t.Errorf("push %s: %v", address, err)
+	}
+}
+
+func TestConcurrentPullPush(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 32)
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(2)
+		go func(i int, address net.HardwareAddr) {
+			defer wg.Done()
+			rename(t, plane, address, fmt.Sprintf("module-%d", i))
+		}(i, address)
+		go func(address net.HardwareAddr) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if _, err := plane.Pull(context.Background(), ch912x.ProductCH9121, address); err != nil {
+					t.Errorf("pull %s: %v", address, err)
+				}
+			}
+		}(address)
+	}
+	wg.Wait()
+	for i, address := range addresses {
+		module, err := plane.Pull(context.Background(), ch912x.ProductCH9121, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := fmt.Sprintf("module-%d", i); module.Name() != name {
+			t.Errorf("%s is named %q, want %q", address, module.Name(), name)
+		}
+		if reboots := network.Device(address).Reboots(); reboots != 1 {
+			t.Errorf("%s rebooted %d times, want 1", address, reboots)
+		}
+		if depth := plane.QueueDepth(address); depth != 0 {
+			t.Errorf("%s has %d operations left in the queue", address, depth)
+		}
+	}
+}
+
+func TestRetriesUnderLossRate(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 16)
+	network.LossRate = 0.5
+	var retransmitted int64
+	plane.OnOperation(func(operation *ch912x.Operation) {
+		atomic.AddInt64(&retransmitted, int64(operation.Attempts-1))
+	})
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address net.HardwareAddr) {
+			defer wg.Done()
+			rename(t, plane, address, fmt.Sprintf("lossy-%d", i))
+		}(i, address)
+	}
+	wg.Wait()
+	network.LossRate = 0
+	if retransmitted == 0 {
+		t.Error("nothing was retransmitted at a loss rate of 0.5")
+	}
+	if stats := plane.Stats(); stats.Retransmitted != uint64(retransmitted) {
+		t.Errorf("stats count %d retransmissions, the operations %d", stats.Retransmitted, retransmitted)
+	}
+	for _, address := range addresses {
+		if reboots := network.Device(address).Reboots(); reboots != 1 {
+			t.Errorf("%s rebooted %d times, want 1", address, reboots)
+		}
+	}
+}
+
+// responseFilter drops the responses of the kind read from the network until the limit.
+type responseFilter struct {
+	*ch912xsim.Conn
+	kind    ch912x.Kind
+	limit   int32
+	dropped int32
+}
+
+func (f *responseFilter) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		if n, addr, err = f.Conn.ReadFrom(p); err != nil {
+			return
+		}
+		module := new(ch912x.CH9121)
+		if _, err := module.ReadFrom(bytes.NewReader(p[:n])); err == nil && module.Kind() == f.kind {
+			if atomic.AddInt32(&f.dropped, 1) <= f.limit {
+				continue
+			}
+		}
+		return
+	}
+}
+
+func TestPushResponseLost(t *testing.T) {
+	network, _, addresses := newNetwork(t, 1)
+	filter := &responseFilter{Conn: network.Listen(), kind: ch912x.KindPushResponse, limit: 1}
+	plane := ch912x.NewControlPlane(filter, network.ListenARP(), clientMAC)
+	configure(plane)
+	defer plane.Close()
+	var attempts int
+	plane.OnOperation(func(operation *ch912x.Operation) {
+		if operation.Kind == ch912x.KindPushRequest {
+			attempts = operation.Attempts
+		}
+	})
+	current, err := plane.Pull(context.Background(), ch912x.ProductCH9121, addresses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := ch912x.MergePatch(current, []byte(`{"module_name":"lost"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := plane.Push(context.Background(), module)
+	if err != nil {
+		t.Fatal(err)
+	} else if parsed != nil {
+		t.Error("the lost response was parsed")
+	}
+	if attempts < 2 {
+		t.Errorf("%d attempts, the push was not retransmitted while the module was rebooting", attempts)
+	}
+	if reboots := network.Device(addresses[0]).Reboots(); reboots != 1 {
+		t.Errorf("the module rebooted %d times, want 1", reboots)
+	}
+}
+
+func TestPushWithoutARPNotRetransmitted(t *testing.T) {
+	network, _, addresses := newNetwork(t, 1)
+	network.LossRate = 1
+	plane := ch912x.NewControlPlane(network.Listen(), nil, clientMAC)
+	configure(plane)
+	defer plane.Close()
+	var attempts int
+	plane.OnOperation(func(operation *ch912x.Operation) {
+		attempts = operation.Attempts
+	})
+	module := ch912xsim.NewCH9121(addresses[0])
+	module.PacketKind = ch912x.KindPushRequest
+	if _, err := plane.Push(context.Background(), module); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if attempts != 1 {
+		t.Errorf("the push was sent %d times without the ARP transport, want 1", attempts)
+	}
+}
+
+// packetConn hands the packets sent on its channel to the control plane and discards the writes.
+type packetConn struct {
+	packets chan []byte
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (c *packetConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	select {
+	case <-c.done:
+		err = net.ErrClosed
+	case packet := <-c.packets:
+		n = copy(p, packet)
+		addr = &net.UDPAddr{IP: net.IPv4(192, 168, 1, 200), Port: 50000}
+	}
+	return
+}
+
+func (c *packetConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	return len(p), nil
+}
+
+func (c *packetConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+func TestDroppedPackets(t *testing.T) {
+	conn := &packetConn{packets: make(chan []byte), done: make(chan struct{})}
+	plane := ch912x.NewControlPlane(conn, nil, clientMAC)
+	defer plane.Close()
+	errs := make(chan *ch912x.PacketError, 3)
+	plane.OnPacketError(func(err *ch912x.PacketError) { errs <- err })
+	conn.packets <- []byte("NOT_A_MODULE")
+	if err := <-errs; !errors.Is(err, ch912x.ErrUnknownModuleType) {
+		t.Errorf("got %v, want %v", err, ch912x.ErrUnknownModuleType)
+	}
+	conn.packets <- []byte("CH9121_CFG_FLAG\x00\x82")
+	if err := <-errs; !errors.Is(err, ch912x.ErrTruncatedPacket) {
+		t.Errorf("got %v, want %v", err, ch912x.ErrTruncatedPacket)
+	} else if !bytes.Equal(err.Data, []byte("CH9121_CFG_FLAG\x00\x82")) {
+		t.Errorf("got the packet %q", err.Data)
+	}
+	stats := plane.Stats()
+	if stats.Received != 2 || stats.Unknown != 1 || stats.Truncated != 1 || stats.Handled != 0 {
+		t.Errorf("got %+v", stats)
+	}
+}
@@ -0,0 +1,113 @@
+package ch912x
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change is a single field difference, Path uses the same dotted JSON names as ValidationError.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// diffIgnored are the fields describing the exchange rather than the configuration.
+var diffIgnored = map[string]bool{
+	"product":    true,
+	"version":    true,
+	"client_mac": true,
+}
+
+// Diff compares two configurations of the same product, the changes are sorted by path.
+func Diff(old, new Module) (changes []Change, err error) {
+	if old.Product() != new.Product() {
+		err = ErrProductMismatch
+		return
+	}
+	oldFields, err := toJSONObject(old)
+	if err != nil {
+		return
+	}
+	newFields, err := toJSONObject(new)
+	if err != nil {
+		return
+	}
+	for key := range diffIgnored {
+		delete(oldFields, key)
+		delete(newFields, key)
+	}
+	fillFalse(oldFields, reflect.TypeOf(old))
+	fillFalse(newFields, reflect.TypeOf(new))
+	changes = diffValue(make([]Change, 0), "", oldFields, newFields)
+	return
+}
+
+func toJSONObject(module Module) (fields map[string]interface{}, err error) {
+	data, err := json.Marshal(module)
+	if err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&fields)
+	return
+}
+
+// fillFalse puts back the false booleans dropped by omitempty, they would be compared with null otherwise.
+func fillFalse(fields map[string]interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" || name == "" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Bool {
+			if _, ok := fields[name]; !ok {
+				fields[name] = false
+			}
+		} else if object, ok := fields[name].(map[string]interface{}); ok {
+			fillFalse(object, field.Type)
+		}
+	}
+}
+
+func diffValue(changes []Change, path string, old, new interface{}) []Change {
+	oldFields, oldIsObject := old.(map[string]interface{})
+	newFields, newIsObject := new.(map[string]interface{})
+	if !oldIsObject && !newIsObject {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, Change{Path: path, Old: old, New: new})
+		}
+		return changes
+	}
+	keys := make(map[string]bool)
+	for key := range oldFields {
+		keys[key] = true
+	}
+	for key := range newFields {
+		keys[key] = true
+	}
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := name
+		if path != "" {
+			field = path + "." + name
+		}
+		changes = diffValue(changes, field, oldFields[name], newFields[name])
+	}
+	return changes
+}
@@ -0,0 +1,61 @@
+package ch912x_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/CursedHardware/ch912x"
+	"github.com/CursedHardware/ch912x/ch912xsim"
+)
+
+func TestDiff(t *testing.T) {
+	old := ch912xsim.NewCH9121(testMAC)
+	changes, err := ch912x.Diff(old, old.Clone())
+	if err != nil {
+		t.Fatal(err)
+	} else if changes == nil || len(changes) != 0 {
+		t.Errorf("got %v, want no change", changes)
+	}
+	// the version and the client MAC describe the exchange, they are not compared
+	new := old.Clone().(*ch912x.CH9121)
+	new.FirmwareVersion = "3"
+	new.ClientMAC = clientMAC
+	new.ModuleName = "renamed"
+	new.UART1.Baud = 115200
+	new.UART1.RandomClientPort = true
+	new.UART2.Parity = ch912x.ParityOdd
+	if changes, err = ch912x.Diff(old, new); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"module_name: CH9121 -> renamed",
+		"uart_1.baud: 9600 -> 115200",
+		"uart_1.random_client_port: false -> true",
+		"uart_2.parity: none -> odd",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %v, want %v", changes, want)
+	}
+	for i, change := range changes {
+		if got := fmt.Sprintf("%s: %v -> %v", change.Path, change.Old, change.New); got != want[i] {
+			t.Errorf("got %s, want %s", got, want[i])
+		}
+	}
+	new.UART2 = nil
+	if changes, err = ch912x.Diff(old, new); err != nil {
+		t.Fatal(err)
+	}
+	// the members of a removed object are compared with null
+	removed := false
+	for _, change := range changes {
+		if change.Path == "uart_2.baud" {
+			removed = fmt.Sprint(change.Old) == "9600" && change.New == nil
+		}
+	}
+	if !removed {
+		t.Errorf("got %v, want uart_2.baud: 9600 -> <nil>", changes)
+	}
+	if _, err = ch912x.Diff(old, ch912xsim.NewCH9120(testMAC)); err != ch912x.ErrProductMismatch {
+		t.Errorf("got %v, want %v", err, ch912x.ErrProductMismatch)
+	}
+}
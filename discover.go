@@ -0,0 +1,109 @@
+package ch912x
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+)
+
+type DiscoverOptions struct {
+	Products    []Product
+	Duration    time.Duration
+	Retransmits int
+}
+
+type DiscoveredModule struct {
+	Module    Module    `json:"module"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Responses int       `json:"responses"`
+}
+
+// Discover broadcasts the discovery requests of the products (all the registered by default)
+// for the duration (3s by default) and collects the responses deduplicated by the module MAC,
+// sorted by the module MAC.
+// The requests are retransmitted evenly during the duration (3 times by default).
+func (p *ControlPlane) Discover(ctx context.Context, options DiscoverOptions) (modules []*DiscoveredModule, err error) {
+	if len(options.Products) == 0 {
+		options.Products = Products()
+	}
+	if options.Duration <= 0 {
+		options.Duration = 3 * time.Second
+	}
+	if options.Retransmits <= 0 {
+		options.Retransmits = 3
+	}
+	products := make(map[Product]bool)
+	for _, product := range options.Products {
+		products[product] = true
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	subscription := p.SubscribeWithOptions(ctx, func(module Module) bool {
+		return products[module.Product()]
+	}, SubscribeOptions{Buffer: 256, Policy: DropOldest})
+	defer subscription.Unsubscribe()
+	broadcast := func() error {
+		for _, product := range options.Products {
+			if err := p.SendDiscovery(product); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err = broadcast(); err != nil {
+		return
+	}
+	deadline := time.NewTimer(options.Duration)
+	defer deadline.Stop()
+	ticker := time.NewTicker(options.Duration / time.Duration(options.Retransmits))
+	defer ticker.Stop()
+	retransmits := 1
+	seen := make(map[string]*DiscoveredModule)
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-ticker.C:
+			if retransmits >= options.Retransmits {
+				continue
+			}
+			retransmits++
+			if err = broadcast(); err != nil {
+				break loop
+			}
+		case module, ok := <-subscription.C:
+			if !ok {
+				break loop
+			}
+			now := time.Now()
+			address := module.MAC()
+			if found, ok := seen[address.String()]; ok {
+				found.Module = module
+				found.LastSeen = now
+				found.Responses++
+			} else {
+				seen[address.String()] = &DiscoveredModule{
+					Module:    module,
+					FirstSeen: now,
+					LastSeen:  now,
+					Responses: 1,
+				}
+			}
+		}
+	}
+	for _, module := range seen {
+		modules = append(modules, module)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		a := modules[i].Module.MAC()
+		b := modules[j].Module.MAC()
+		return bytes.Compare(a, b) < 0
+	})
+	return
+}
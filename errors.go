@@ -3,12 +3,29 @@ package ch912x
 import "errors"
 
 var (
-	ErrInvalidNetworkInterface = errors.New("ch912x: invalid network interface")
-	ErrCH9120InvalidJSON       = errors.New("ch912x: the JSON not is CH9120 configuration")
-	ErrCH9121InvalidJSON       = errors.New("ch912x: the JSON not is CH9121 configuration")
-	ErrCH9126InvalidJSON       = errors.New("ch912x: the JSON not is CH9126 configuration")
-	ErrModuleKindWrong         = errors.New("ch912x: the module kind wrong")
-	ErrModuleMustMAC           = errors.New("ch912x: the need to provide `ModuleMAC`")
-	ErrTaskRunning             = errors.New("ch912x: the previous task was not completed")
-	ErrUnknownModuleType       = errors.New("ch912x: unknown module type")
+	ErrInvalidNetworkInterface  = errors.New("ch912x: invalid network interface")
+	ErrBindInterfaceUnsupported = errors.New("ch912x: binding to network interface is not supported on this platform")
+	ErrCH9120InvalidJSON        = errors.New("ch912x: the JSON not is CH9120 configuration")
+	ErrCH9121InvalidJSON        = errors.New("ch912x: the JSON not is CH9121 configuration")
+	ErrCH9126InvalidJSON        = errors.New("ch912x: the JSON not is CH9126 configuration")
+	ErrNetModuleInvalidJSON     = errors.New("ch912x: the JSON not is NET_MODULE configuration")
+	ErrModuleKindWrong          = errors.New("ch912x: the module kind wrong")
+	ErrModuleMustMAC            = errors.New("ch912x: the need to provide `ModuleMAC`")
+	ErrTaskRunning              = errors.New("ch912x: the previous task was not completed")
+	ErrUnknownModuleType        = errors.New("ch912x: unknown module type")
+	ErrTruncatedPacket          = errors.New("ch912x: the packet is truncated")
+	ErrProductMismatch          = errors.New("ch912x: the modules are different products")
+	ErrPushNotApplied           = errors.New("ch912x: the module did not apply the pushed configuration")
+	ErrModuleOffline            = errors.New("ch912x: the module did not come back online")
+	ErrModuleMACChanged         = errors.New("ch912x: the update changed the module MAC")
+	ErrProbeUnsupported         = errors.New("ch912x: the ARP transport cannot send probes")
+	ErrInvalidProbeAddress      = errors.New("ch912x: the probe address is not IPv4")
+	ErrInvalidPool              = errors.New("ch912x: the pool must be an IPv4 CIDR")
+	ErrPoolExhausted            = errors.New("ch912x: the pool has not enough addresses")
+	ErrModuleNotFound           = errors.New("ch912x: the module was not discovered")
+	ErrProductRegistered        = errors.New("ch912x: the product is already registered")
+	ErrInvalidProductDescriptor = errors.New("ch912x: the product descriptor is incomplete")
+	ErrInvalidMergePatch        = errors.New("ch912x: the merge patch must be a JSON object")
+	ErrUnknownName              = errors.New("ch912x: unknown name")
+	ErrInvalidMAC               = errors.New("ch912x: the MAC address is neither aa:bb:cc:dd:ee:ff nor base64")
 )
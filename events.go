@@ -0,0 +1,118 @@
+package ch912x
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type PacketError struct {
+	Addr net.Addr
+	Data []byte
+	Err  error
+}
+
+func (e *PacketError) Error() string {
+	return fmt.Sprintf("ch912x: dropped packet from %v: %v", e.Addr, e.Err)
+}
+
+func (e *PacketError) Unwrap() error {
+	return e.Err
+}
+
+type Stats struct {
+	Received      uint64 `json:"received"`
+	Handled       uint64 `json:"handled"`
+	Unknown       uint64 `json:"unknown"`
+	Truncated     uint64 `json:"truncated"`
+	Invalid       uint64 `json:"invalid"`
+	Retransmitted uint64 `json:"retransmitted"`
+}
+
+// Operation reports a request sent to the module, Attempts counts the retransmissions too.
+type Operation struct {
+	Kind     Kind
+	Address  net.HardwareAddr
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+type hookSet struct {
+	mutex sync.Mutex
+	next  int
+	hooks map[int]interface{}
+}
+
+func (h *hookSet) add(fn interface{}) (remove func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.hooks == nil {
+		h.hooks = make(map[int]interface{})
+	}
+	id := h.next
+	h.next++
+	h.hooks[id] = fn
+	return func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		delete(h.hooks, id)
+	}
+}
+
+func (h *hookSet) snapshot() (hooks []interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, fn := range h.hooks {
+		hooks = append(hooks, fn)
+	}
+	return
+}
+
+// OnPacketError subscribes to the packets dropped by the control plane,
+// the hook is called from the receiving goroutines and must not block.
+func (p *ControlPlane) OnPacketError(fn func(*PacketError)) (remove func()) {
+	return p.packetErrors.add(fn)
+}
+
+// OnOperation subscribes to the completed requests, the hook is called from the requesting goroutine.
+func (p *ControlPlane) OnOperation(fn func(*Operation)) (remove func()) {
+	return p.operations.add(fn)
+}
+
+func (p *ControlPlane) Stats() Stats {
+	return Stats{
+		Received:      atomic.LoadUint64(&p.stats.Received),
+		Handled:       atomic.LoadUint64(&p.stats.Handled),
+		Unknown:       atomic.LoadUint64(&p.stats.Unknown),
+		Truncated:     atomic.LoadUint64(&p.stats.Truncated),
+		Invalid:       atomic.LoadUint64(&p.stats.Invalid),
+		Retransmitted: atomic.LoadUint64(&p.stats.Retransmitted),
+	}
+}
+
+func (p *ControlPlane) dropPacket(addr net.Addr, data []byte, err error) {
+	switch err {
+	case ErrUnknownModuleType:
+		atomic.AddUint64(&p.stats.Unknown, 1)
+	case ErrTruncatedPacket:
+		atomic.AddUint64(&p.stats.Truncated, 1)
+	default:
+		atomic.AddUint64(&p.stats.Invalid, 1)
+	}
+	packetErr := &PacketError{Addr: addr, Data: data, Err: err}
+	for _, fn := range p.packetErrors.snapshot() {
+		fn.(func(*PacketError))(packetErr)
+	}
+}
+
+func (p *ControlPlane) reportOperation(operation *Operation) {
+	if operation.Attempts > 1 {
+		atomic.AddUint64(&p.stats.Retransmitted, uint64(operation.Attempts-1))
+	}
+	for _, fn := range p.operations.snapshot() {
+		fn.(func(*Operation))(operation)
+	}
+}
@@ -10,4 +10,5 @@ require (
 	golang.org/x/net v0.10.0 // indirect
 	golang.org/x/sync v0.2.0
 	gopkg.in/antage/eventsource.v1 v1.0.0-20150318155416-803f4c5af225
+	gopkg.in/yaml.v3 v3.0.1
 )
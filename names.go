@@ -0,0 +1,184 @@
+package ch912x
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// enumNames names the values of a byte enum, the values without a name are written in decimal.
+type enumNames struct {
+	kind  string
+	names map[byte]string
+}
+
+var (
+	kindNames = &enumNames{kind: "kind", names: map[byte]string{
+		byte(KindPushRequest):       "push_request",
+		byte(KindPullRequest):       "pull_request",
+		byte(KindResetRequest):      "reset_request",
+		byte(KindDiscoveryRequest):  "discovery_request",
+		byte(KindPushResponse):      "push_response",
+		byte(KindPullResponse):      "pull_response",
+		byte(KindResetResponse):     "reset_response",
+		byte(KindDiscoveryResponse): "discovery_response",
+	}}
+	uartModeNames = &enumNames{kind: "uart mode", names: map[byte]string{
+		byte(TCPServer): "tcp_server",
+		byte(TCPClient): "tcp_client",
+		byte(UDPServer): "udp_server",
+		byte(UDPClient): "udp_client",
+	}}
+	uartParityNames = &enumNames{kind: "uart parity", names: map[byte]string{
+		byte(ParityNone):  "none",
+		byte(ParityEven):  "even",
+		byte(ParityOdd):   "odd",
+		byte(ParityMark):  "mark",
+		byte(ParitySpace): "space",
+	}}
+	ntpModeNames = &enumNames{kind: "ntp mode", names: map[byte]string{
+		byte(NTPServer): "server",
+		byte(NTPClient): "client",
+	}}
+)
+
+func (e *enumNames) name(value byte) string {
+	if name, ok := e.names[value]; ok {
+		return name
+	}
+	return strconv.Itoa(int(value))
+}
+
+func (e *enumNames) parse(text []byte) (value byte, err error) {
+	name := strings.ToLower(string(text))
+	for value, known := range e.names {
+		if known == name {
+			return value, nil
+		}
+	}
+	n, err := strconv.ParseUint(name, 10, 8)
+	if err != nil {
+		err = fmt.Errorf("%w: %s %q", ErrUnknownName, e.kind, text)
+	}
+	return byte(n), err
+}
+
+// unmarshalJSON accepts the names and the bare numbers written before the names.
+func (e *enumNames) unmarshalJSON(data []byte, value *byte) (err error) {
+	var text string
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		err = json.Unmarshal(data, &text)
+	} else if !bytes.Equal(data, []byte("null")) {
+		text = string(data)
+	} else {
+		return
+	}
+	if err == nil {
+		*value, err = e.parse([]byte(text))
+	}
+	return
+}
+
+func (k Kind) String() string {
+	return kindNames.name(byte(k))
+}
+
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *Kind) UnmarshalText(text []byte) (err error) {
+	value, err := kindNames.parse(text)
+	*k = Kind(value)
+	return
+}
+
+func (k *Kind) UnmarshalJSON(data []byte) error {
+	return kindNames.unmarshalJSON(data, (*byte)(k))
+}
+
+func (m UARTMode) String() string {
+	return uartModeNames.name(byte(m))
+}
+
+func (m UARTMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *UARTMode) UnmarshalText(text []byte) (err error) {
+	value, err := uartModeNames.parse(text)
+	*m = UARTMode(value)
+	return
+}
+
+func (m *UARTMode) UnmarshalJSON(data []byte) error {
+	return uartModeNames.unmarshalJSON(data, (*byte)(m))
+}
+
+func (p UARTParity) String() string {
+	return uartParityNames.name(byte(p))
+}
+
+func (p UARTParity) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *UARTParity) UnmarshalText(text []byte) (err error) {
+	value, err := uartParityNames.parse(text)
+	*p = UARTParity(value)
+	return
+}
+
+func (p *UARTParity) UnmarshalJSON(data []byte) error {
+	return uartParityNames.unmarshalJSON(data, (*byte)(p))
+}
+
+func (m NTPMode) String() string {
+	return ntpModeNames.name(byte(m))
+}
+
+func (m NTPMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *NTPMode) UnmarshalText(text []byte) (err error) {
+	value, err := ntpModeNames.parse(text)
+	*m = NTPMode(value)
+	return
+}
+
+func (m *NTPMode) UnmarshalJSON(data []byte) error {
+	return ntpModeNames.unmarshalJSON(data, (*byte)(m))
+}
+
+// macAddress writes net.HardwareAddr as aa:bb:cc:dd:ee:ff instead of base64,
+// the base64 written before is still accepted.
+type macAddress net.HardwareAddr
+
+func (m macAddress) MarshalText() ([]byte, error) {
+	return []byte(net.HardwareAddr(m).String()), nil
+}
+
+func (m *macAddress) UnmarshalText(text []byte) (err error) {
+	address, err := ParseMAC(string(text))
+	*m = macAddress(address)
+	return
+}
+
+// ParseMAC parses the 6 bytes of aa:bb:cc:dd:ee:ff and the other forms of net.ParseMAC, or base64 as written before.
+func ParseMAC(s string) (address net.HardwareAddr, err error) {
+	if s == "" {
+		return
+	}
+	if address, err = net.ParseMAC(s); err != nil {
+		address, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil || len(address) != 6 {
+		address, err = nil, ErrInvalidMAC
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package ch912x_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+// TestLegacyJSON reads the numbers and the base64 MAC written before the names.
+func TestLegacyJSON(t *testing.T) {
+	mac := base64.StdEncoding.EncodeToString(testMAC)
+	legacy := `{"product":"CH9121","module_mac":"` + mac + `","module_options":{"mac":"` + mac + `"},` +
+		`"uart_1":{"mode":1,"parity":2},"uart_2":{"mode":"3","parity":"MARK"}}`
+	module := new(ch912x.CH9121)
+	if err := json.Unmarshal([]byte(legacy), module); err != nil {
+		t.Fatal(err)
+	}
+	if module.ModuleMAC.String() != testMAC.String() || module.ModuleOptions.MAC.String() != testMAC.String() {
+		t.Errorf("got %s and %s, want %s", module.ModuleMAC, module.ModuleOptions.MAC, testMAC)
+	}
+	if module.UART1.Mode != ch912x.TCPClient || module.UART1.Parity != ch912x.ParityOdd ||
+		module.UART2.Mode != ch912x.UDPClient || module.UART2.Parity != ch912x.ParityMark {
+		t.Errorf("got %+v and %+v", module.UART1, module.UART2)
+	}
+	data, err := json.Marshal(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, written := range []string{`"module_mac":"02:91:00:00:00:01"`, `"mode":"tcp_client"`, `"parity":"odd"`, `"mode":"udp_client"`, `"parity":"mark"`} {
+		if !strings.Contains(string(data), written) {
+			t.Errorf("%s is missing from %s", written, data)
+		}
+	}
+}
+
+func TestLegacyJSONRejected(t *testing.T) {
+	for _, test := range []struct {
+		module   ch912x.Module
+		document string
+		err      error
+	}{
+		{new(ch912x.CH9121), `{"product":"CH9121","uart_1":{"mode":"serial"}}`, ch912x.ErrUnknownName},
+		{new(ch912x.CH9121), `{"product":"CH9121","uart_1":{"parity":256}}`, ch912x.ErrUnknownName},
+		{new(ch912x.CH9126), `{"product":"CH9126","ntp":{"mode":"-1"}}`, ch912x.ErrUnknownName},
+		{new(ch912x.CH9121), `{"product":"CH9121","module_mac":"AAAA"}`, ch912x.ErrInvalidMAC},
+		{new(ch912x.CH9121), `{"product":"CH9121","module_mac":"02:00:00:00:00:00:00:01"}`, ch912x.ErrInvalidMAC},
+	} {
+		if err := json.Unmarshal([]byte(test.document), test.module); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.document, err, test.err)
+		}
+	}
+}
+
+func TestParseMAC(t *testing.T) {
+	for _, s := range []string{"02:91:00:00:00:01", "02-91-00-00-00-01", "0291.0000.0001", base64.StdEncoding.EncodeToString(testMAC)} {
+		if address, err := ch912x.ParseMAC(s); err != nil || address.String() != testMAC.String() {
+			t.Errorf("%s: got %s %v", s, address, err)
+		}
+	}
+	if address, err := ch912x.ParseMAC(""); address != nil || err != nil {
+		t.Errorf("got %s %v for the empty string", address, err)
+	}
+}
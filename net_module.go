@@ -0,0 +1,252 @@
+package ch912x
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+)
+
+// NetModule is the generic WCH network module speaking the NET_MODULE_COMM format,
+// the layout follows the packets built and parsed by the vendor tool docs/CH9121/NetModuleConfig.exe.
+type NetModule struct {
+	PacketKind    Kind             `json:"-"`
+	ModuleName    string           `json:"module_name,omitempty"`
+	ModuleMAC     net.HardwareAddr `json:"module_mac,omitempty"`
+	ClientMAC     net.HardwareAddr `json:"client_mac,omitempty"`
+	ModuleOptions *ModuleOptions   `json:"module_options,omitempty"`
+	UART1         *UARTService     `json:"uart_1,omitempty"`
+	raw           []byte
+}
+
+func (p *NetModule) Product() Product {
+	return ProductNetModule
+}
+
+func (p *NetModule) Kind() Kind {
+	return p.PacketKind
+}
+
+func (p *NetModule) MAC() net.HardwareAddr {
+	return p.ModuleMAC
+}
+
+func (p *NetModule) IP() net.IP {
+	if p.ModuleOptions == nil {
+		return nil
+	}
+	return p.ModuleOptions.IP
+}
+
+func (p *NetModule) Name() string {
+	return p.ModuleName
+}
+
+func (p *NetModule) Version() string {
+	return ""
+}
+
+func (p *NetModule) Options() *ModuleOptions {
+	return p.ModuleOptions
+}
+
+func (p *NetModule) ClientAddress() net.HardwareAddr {
+	return p.ClientMAC
+}
+
+func (p *NetModule) Clone() Module {
+	cloned := *p
+	cloned.ModuleMAC = cloneBytes(p.ModuleMAC)
+	cloned.ClientMAC = cloneBytes(p.ClientMAC)
+	cloned.ModuleOptions = p.ModuleOptions.clone()
+	cloned.UART1 = p.UART1.clone()
+	cloned.raw = cloneBytes(p.raw)
+	return &cloned
+}
+
+func (p *NetModule) SetKind(kind Kind) {
+	p.PacketKind = kind
+}
+
+func (p *NetModule) SetVersion(string) {}
+
+func (p *NetModule) SetClientMAC(addr net.HardwareAddr) {
+	p.ClientMAC = addr
+}
+
+func (p *NetModule) Raw() []byte {
+	return p.raw
+}
+
+func (p *NetModule) SetRaw(raw []byte) {
+	p.raw = raw
+}
+
+func (p *NetModule) MarshalJSON() ([]byte, error) {
+	type Module NetModule
+	module := new(struct {
+		Product   Product    `json:"product"`
+		ModuleMAC macAddress `json:"module_mac,omitempty"`
+		ClientMAC macAddress `json:"client_mac,omitempty"`
+		*Module
+	})
+	module.Product = ProductNetModule
+	module.ModuleMAC = macAddress(p.ModuleMAC)
+	module.ClientMAC = macAddress(p.ClientMAC)
+	module.Module = (*Module)(p)
+	return json.Marshal(module)
+}
+
+func (p *NetModule) UnmarshalJSON(data []byte) (err error) {
+	type Module NetModule
+	module := new(struct {
+		Product   Product     `json:"product"`
+		ModuleMAC *macAddress `json:"module_mac,omitempty"`
+		ClientMAC *macAddress `json:"client_mac,omitempty"`
+		*Module
+	})
+	module.ModuleMAC = (*macAddress)(&p.ModuleMAC)
+	module.ClientMAC = (*macAddress)(&p.ClientMAC)
+	module.Module = (*Module)(p)
+	err = json.Unmarshal(data, module)
+	if err == nil && module.Product != ProductNetModule {
+		err = ErrNetModuleInvalidJSON
+	}
+	return
+}
+
+func (p *NetModule) ReadFrom(r io.Reader) (n int64, err error) {
+	header := new(netModuleHeader)
+	err = binary.Read(r, binary.LittleEndian, header)
+	if err != nil {
+		return
+	}
+	body, err := io.ReadAll(r)
+	n = int64(binary.Size(header) + len(body))
+	if err != nil {
+		return
+	}
+	p.raw = body
+	p.PacketKind = header.Kind
+	p.ModuleMAC = header.ModuleMAC[:]
+	p.ClientMAC = header.ClientMAC[:]
+	c := new(netModuleConfiguration)
+	size := binary.Size(c)
+	if len(body) < size {
+		// the discovery response carries the name alone, the tool reads it whatever the length says
+		body = append(body, make([]byte, size-len(body))...)
+	}
+	_ = binary.Read(bytes.NewReader(body), binary.LittleEndian, c)
+	p.ModuleName = trimNull(c.ModuleName[:])
+	if int(c.Length) < size-1 {
+		return
+	} else if len(p.raw) < size {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	p.ModuleOptions = &ModuleOptions{
+		IP:      c.IP[:],
+		Mask:    c.Mask[:],
+		Gateway: c.Gateway[:],
+	}
+	p.UART1 = &UARTService{
+		Mode:          UARTMode(c.Mode),
+		ClientIP:      c.ClientIP[:],
+		ClientPort:    c.ClientPort,
+		LocalPort:     c.LocalPort,
+		PacketTimeout: uint16(c.PacketTimeout),
+		Baud:          c.Baud,
+		DataBits:      c.DataBits,
+		StopBit:       c.StopBit,
+		Parity:        fromNetModuleParity(c.Parity),
+	}
+	return
+}
+
+func (p *NetModule) WriteTo(w io.Writer) (n int64, err error) {
+	var buf bytes.Buffer
+	h := &netModuleHeader{Kind: p.PacketKind}
+	copy(h.Header[:], magicModule)
+	copy(h.ModuleMAC[:], p.ModuleMAC)
+	copy(h.ClientMAC[:], p.ClientMAC)
+	_ = binary.Write(&buf, binary.LittleEndian, h)
+	r := new(netModuleConfiguration)
+	size := binary.Size(r)
+	_ = binary.Read(bytes.NewReader(append(p.raw, make([]byte, size)...)), binary.LittleEndian, r)
+	setString(r.ModuleName[:], p.ModuleName)
+	if p.ModuleOptions != nil || p.UART1 != nil {
+		r.Length = byte(size - 1)
+	}
+	if opt := p.ModuleOptions; opt != nil {
+		copyIP(r.IP[:], opt.IP)
+		copyIP(r.Mask[:], opt.Mask)
+		copyIP(r.Gateway[:], opt.Gateway)
+	}
+	if uart := p.UART1; uart != nil {
+		r.Mode = byte(uart.Mode)
+		copyIP(r.ClientIP[:], uart.ClientIP)
+		r.ClientPort = uart.ClientPort
+		r.LocalPort = uart.LocalPort
+		if uint16(r.PacketTimeout) != uart.PacketTimeout {
+			r.PacketTimeout = uint32(uart.PacketTimeout)
+		}
+		r.Baud = uart.Baud
+		r.DataBits = uart.DataBits
+		r.StopBit = uart.StopBit
+		setNetModuleParity(&r.Parity, uart.Parity)
+	}
+	_, _ = buf.Write(encodeWithRaw(r, p.raw))
+	if buf.Len() < 285 {
+		_, _ = buf.Write(make([]byte, 285-buf.Len()))
+	}
+	return buf.WriteTo(w)
+}
+
+// netModuleParities is the order of the parity list of the NetModuleConfig tool.
+var netModuleParities = []UARTParity{ParityOdd, ParityEven, ParityMark, ParitySpace, ParityNone}
+
+func fromNetModuleParity(p byte) UARTParity {
+	if int(p) < len(netModuleParities) {
+		return netModuleParities[p]
+	}
+	return UARTParity(p)
+}
+
+func setNetModuleParity(dst *byte, p UARTParity) {
+	if fromNetModuleParity(*dst) == p {
+		return
+	}
+	for i, parity := range netModuleParities {
+		if parity == p {
+			*dst = byte(i)
+		}
+	}
+}
+
+type netModuleHeader struct {
+	Header    [16]byte // NET_MODULE_COMM
+	Kind      Kind
+	ModuleMAC [6]byte
+	ClientMAC [6]byte
+}
+
+// netModuleConfiguration follows the header, the discovery response carries ModuleName alone.
+type netModuleConfiguration struct {
+	Length        byte // of the configuration following it, 55
+	ModuleName    [21]byte
+	Mode          byte // TCP Server, TCP Client, UDP Server, UDP Client
+	IP            [4]byte
+	Mask          [4]byte
+	Gateway       [4]byte
+	Baud          uint32
+	Parity        byte // Odd, Even, Mark, Space, None
+	DataBits      byte
+	StopBit       byte
+	PacketTimeout uint32
+	LocalPort     uint16
+	ClientIP      [4]byte
+	ClientPort    uint16
+	_             byte // retries
+	_             byte // clear the serial buffer on connect
+}
@@ -0,0 +1,100 @@
+package ch912x
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"unicode/utf8"
+)
+
+// MergePatch applies a JSON Merge Patch (RFC 7386) onto the module and returns a push request,
+// the bytes the patch cannot express (reserved fields, unknown tail) are carried from the module.
+func MergePatch(module Module, patch []byte) (patched Module, err error) {
+	var changes interface{}
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.UseNumber()
+	if err = decoder.Decode(&changes); err != nil {
+		return
+	} else if _, ok := changes.(map[string]interface{}); !ok {
+		err = ErrInvalidMergePatch
+		return
+	}
+	descriptor := LookupProduct(module.Product())
+	if descriptor == nil {
+		err = ErrUnknownModuleType
+		return
+	}
+	fields, err := toJSONObject(module)
+	if err != nil {
+		return
+	}
+	merged := mergePatch(fields, changes).(map[string]interface{})
+	merged["product"] = descriptor.Product
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return
+	}
+	address := module.MAC()
+	patched = descriptor.Request(KindPushRequest, address)
+	if raw, ok := module.(RawModule); ok {
+		if patched, ok := patched.(RawModule); ok {
+			patched.SetRaw(raw.Raw())
+		}
+	}
+	if err = json.Unmarshal(data, patched); err != nil {
+		patched = nil
+		return
+	}
+	if reflect.TypeOf(patched) == reflect.TypeOf(module) {
+		restoreStrings(reflect.ValueOf(patched), reflect.ValueOf(module))
+	}
+	return
+}
+
+// restoreStrings puts back the strings of the module the patch left alone, JSON turns the bytes
+// that are not UTF-8 into U+FFFD and the push would write them over the name or the domain.
+func restoreStrings(patched, module reflect.Value) {
+	switch patched.Kind() {
+	case reflect.Ptr:
+		if !patched.IsNil() && !module.IsNil() {
+			restoreStrings(patched.Elem(), module.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < patched.NumField(); i++ {
+			if patched.Type().Field(i).PkgPath == "" {
+				restoreStrings(patched.Field(i), module.Field(i))
+			}
+		}
+	case reflect.String:
+		// the conversion replaces every invalid byte by U+FFFD the way encoding/json does
+		if s := module.String(); !utf8.ValidString(s) && patched.String() == string([]rune(s)) {
+			patched.SetString(s)
+		}
+	}
+}
+
+// RawModule is implemented by the modules keeping the configuration bytes they were read from,
+// MergePatch carries them over so the reserved fields are written back as pulled.
+type RawModule interface {
+	Raw() []byte
+	SetRaw(raw []byte)
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	fields, ok := target.(map[string]interface{})
+	if !ok {
+		fields = make(map[string]interface{})
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(fields, name)
+		} else {
+			fields[name] = mergePatch(fields[name], value)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,76 @@
+package ch912x_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+func TestMergePatch(t *testing.T) {
+	module := &ch912x.CH9121{
+		PacketKind:    ch912x.KindPullResponse,
+		ModuleName:    "CH9121",
+		ModuleMAC:     testMAC,
+		ModuleOptions: &ch912x.ModuleOptions{UseDHCP: true},
+		UART1:         &ch912x.UARTService{Mode: ch912x.TCPClient, Baud: 115200, DataBits: 8, StopBit: 1, ClientDomain: "example.com"},
+		UART2:         &ch912x.UARTService{Baud: 9600, DataBits: 8, StopBit: 1},
+	}
+	patched, err := ch912x.MergePatch(module, []byte(`{"module_name":"renamed","uart_1":{"baud":9600,"client_domain":null},"uart_2":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := patched.(*ch912x.CH9121)
+	if got.Kind() != ch912x.KindPushRequest || !bytes.Equal(got.MAC(), testMAC) {
+		t.Errorf("got the %v request for %s", got.Kind(), got.MAC())
+	}
+	if got.ModuleName != "renamed" || got.ModuleOptions == nil || !got.ModuleOptions.UseDHCP {
+		t.Errorf("got %q %+v", got.ModuleName, got.ModuleOptions)
+	}
+	// the members left out of the patch are kept, null removes them
+	if uart := got.UART1; uart.Mode != ch912x.TCPClient || uart.Baud != 9600 || uart.DataBits != 8 || uart.ClientDomain != "" {
+		t.Errorf("got uart_1 %+v", uart)
+	}
+	if got.UART2 != nil {
+		t.Errorf("got uart_2 %+v", got.UART2)
+	}
+	if module.ModuleName != "CH9121" || module.UART1.Baud != 115200 || module.UART2 == nil {
+		t.Error("the patched module is modified")
+	}
+	for _, patch := range []string{`[]`, `"name"`, `null`} {
+		if _, err := ch912x.MergePatch(module, []byte(patch)); err != ch912x.ErrInvalidMergePatch {
+			t.Errorf("%s: got %v, want %v", patch, err, ch912x.ErrInvalidMergePatch)
+		}
+	}
+}
+
+// TestMergePatchKeepsBytes pushes back what is pulled through an empty patch, the reserved bytes
+// and the names that are not UTF-8 must not move.
+func TestMergePatchKeepsBytes(t *testing.T) {
+	for _, product := range []ch912x.Product{ch912x.ProductCH9120, ch912x.ProductCH9121, ch912x.ProductCH9126, ch912x.ProductNetModule} {
+		pulled, kind := pulledPacket(t, product)
+		module := ch912x.LookupProduct(product).New()
+		if _, err := module.ReadFrom(bytes.NewReader(pulled)); err != nil {
+			t.Fatal(err)
+		}
+		patched, err := ch912x.MergePatch(module, []byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if patched.Name() != module.Name() {
+			t.Errorf("%s: the name %q is patched into %q", product, module.Name(), patched.Name())
+		}
+		var pushed bytes.Buffer
+		if _, err := patched.WriteTo(&pushed); err != nil {
+			t.Fatal(err)
+		}
+		want := append([]byte(nil), pulled...)
+		want[kind] = byte(ch912x.KindPushRequest)
+		got := pushed.Bytes()
+		for i := range want {
+			if i < len(got) && got[i] != want[i] {
+				t.Errorf("%s: byte %#x is %#02x, pulled %#02x", product, i, got[i], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ch912x
+
+import (
+	"bytes"
+	"net"
+	"sync"
+)
+
+// ProductDescriptor plugs a product into the control plane, the API and the tools.
+// New returns an empty module for decoding the packets starting with Magic,
+// Request builds the request of the kind addressed to the module MAC (nil for the discovery)
+// and Capabilities describes the product running the firmware version, the baseline when it is empty.
+type ProductDescriptor struct {
+	Product      Product
+	Magic        string
+	New          func() Module
+	Request      func(kind Kind, address net.HardwareAddr) Module
+	Capabilities func(version string) *Capabilities
+}
+
+var productRegistry = struct {
+	sync.RWMutex
+	descriptors []*ProductDescriptor
+	byProduct   map[Product]*ProductDescriptor
+}{
+	byProduct: make(map[Product]*ProductDescriptor),
+}
+
+func init() {
+	for _, descriptor := range []*ProductDescriptor{
+		{
+			Product: ProductCH9120,
+			Magic:   magicCH9120,
+			New:     func() Module { return new(CH9120) },
+			Request: func(kind Kind, address net.HardwareAddr) Module {
+				return &CH9120{PacketKind: kind, ModuleMAC: address}
+			},
+			Capabilities: ch9120Firmware.of,
+		},
+		{
+			Product: ProductCH9121,
+			Magic:   magicCH9121,
+			New:     func() Module { return new(CH9121) },
+			Request: func(kind Kind, address net.HardwareAddr) Module {
+				return &CH9121{PacketKind: kind, ModuleMAC: address}
+			},
+			Capabilities: ch9121Firmware.of,
+		},
+		{
+			Product: ProductCH9126,
+			Magic:   magicCH9126,
+			New:     func() Module { return new(CH9126) },
+			Request: func(kind Kind, address net.HardwareAddr) Module {
+				return &CH9126{PacketKind: kind, ModuleMAC: address}
+			},
+			Capabilities: ch9126Firmware.of,
+		},
+		{
+			Product: ProductNetModule,
+			Magic:   magicModule,
+			New:     func() Module { return new(NetModule) },
+			Request: func(kind Kind, address net.HardwareAddr) Module {
+				return &NetModule{PacketKind: kind, ModuleMAC: address}
+			},
+			Capabilities: netModuleFirmware.of,
+		},
+	} {
+		if err := RegisterProduct(descriptor); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterProduct adds the product, the products registered first are discovered first.
+func RegisterProduct(descriptor *ProductDescriptor) error {
+	if descriptor.Product == "" || descriptor.Magic == "" || descriptor.New == nil || descriptor.Request == nil || descriptor.Capabilities == nil {
+		return ErrInvalidProductDescriptor
+	}
+	productRegistry.Lock()
+	defer productRegistry.Unlock()
+	if _, ok := productRegistry.byProduct[descriptor.Product]; ok {
+		return ErrProductRegistered
+	}
+	productRegistry.descriptors = append(productRegistry.descriptors, descriptor)
+	productRegistry.byProduct[descriptor.Product] = descriptor
+	return nil
+}
+
+func LookupProduct(product Product) *ProductDescriptor {
+	productRegistry.RLock()
+	defer productRegistry.RUnlock()
+	return productRegistry.byProduct[product]
+}
+
+func Products() (products []Product) {
+	productRegistry.RLock()
+	defer productRegistry.RUnlock()
+	for _, descriptor := range productRegistry.descriptors {
+		products = append(products, descriptor.Product)
+	}
+	return
+}
+
+// lookupMagic finds the product of the packet, the longest magic wins.
+func lookupMagic(data []byte) (found *ProductDescriptor) {
+	productRegistry.RLock()
+	defer productRegistry.RUnlock()
+	for _, descriptor := range productRegistry.descriptors {
+		if !bytes.HasPrefix(data, []byte(descriptor.Magic)) {
+			continue
+		} else if found == nil || len(descriptor.Magic) > len(found.Magic) {
+			found = descriptor
+		}
+	}
+	return
+}
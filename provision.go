@@ -0,0 +1,147 @@
+package ch912x
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ProvisionOptions struct {
+	Pool        string             // CIDR, the addresses start from its host part, e.g. 192.168.1.100/24
+	Gateway     net.IP             // skipped in the pool
+	Mask        net.IP             // the pool mask by default
+	Name        string             // "{n}" is replaced with the 1-based index, e.g. line3-{n}
+	Modules     []net.HardwareAddr // the discovered modules by default
+	Concurrency int                // 4 by default
+	Discover    DiscoverOptions
+}
+
+type ProvisionResult struct {
+	Product Product          `json:"product,omitempty"`
+	MAC     net.HardwareAddr `json:"mac"`
+	IP      net.IP           `json:"ip,omitempty"`
+	Name    string           `json:"name,omitempty"`
+	Err     error            `json:"-"`
+}
+
+func (r *ProvisionResult) MarshalJSON() ([]byte, error) {
+	type Result ProvisionResult
+	result := struct {
+		MAC   string `json:"mac"`
+		Error string `json:"error,omitempty"`
+		*Result
+	}{MAC: r.MAC.String(), Result: (*Result)(r)}
+	if r.Err != nil {
+		result.Error = r.Err.Error()
+	}
+	return json.Marshal(result)
+}
+
+// Provision assigns the addresses of the pool to the modules in the MAC order and pushes them,
+// the pool is checked to cover every module before anything is pushed.
+func (p *ControlPlane) Provision(ctx context.Context, options ProvisionOptions) (results []*ProvisionResult, err error) {
+	first, pool, err := net.ParseCIDR(options.Pool)
+	if err != nil || first.To4() == nil {
+		err = ErrInvalidPool
+		return
+	}
+	mask := options.Mask.To4()
+	if mask == nil {
+		mask = net.IP(pool.Mask).To4()
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+	discovered, err := p.Discover(ctx, options.Discover)
+	if err != nil {
+		return
+	}
+	selected := make(map[string]bool)
+	for _, address := range options.Modules {
+		selected[address.String()] = true
+	}
+	for _, found := range discovered {
+		address := found.Module.MAC()
+		if len(selected) > 0 && !selected[address.String()] {
+			continue
+		}
+		delete(selected, address.String())
+		results = append(results, &ProvisionResult{Product: found.Module.Product(), MAC: address})
+	}
+	provisioned := len(results)
+	addresses, err := allocatePool(first.To4(), pool, options.Gateway.To4(), provisioned)
+	if err != nil {
+		return
+	}
+	for i, result := range results {
+		result.IP = addresses[i]
+		result.Name = strings.ReplaceAll(options.Name, "{n}", strconv.Itoa(i+1))
+	}
+	for _, address := range options.Modules {
+		if selected[address.String()] {
+			results = append(results, &ProvisionResult{MAC: address, Err: ErrModuleNotFound})
+		}
+	}
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, options.Concurrency)
+	for _, result := range results[:provisioned] {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(result *ProvisionResult) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			result.Err = p.provision(ctx, result, mask, options.Gateway)
+		}(result)
+	}
+	wg.Wait()
+	return
+}
+
+func (p *ControlPlane) provision(ctx context.Context, result *ProvisionResult, mask, gateway net.IP) (err error) {
+	options := map[string]interface{}{
+		"ip":       result.IP,
+		"mask":     mask,
+		"use_dhcp": false,
+	}
+	if gateway != nil {
+		options["gateway"] = gateway
+	}
+	changes := map[string]interface{}{"module_options": options}
+	if result.Name != "" {
+		changes["module_name"] = result.Name
+	}
+	patch, err := json.Marshal(changes)
+	if err != nil {
+		return
+	}
+	_, err = p.Update(ctx, result.Product, result.MAC, func(current Module) (Module, error) {
+		return MergePatch(current, patch)
+	})
+	return
+}
+
+func allocatePool(first net.IP, pool *net.IPNet, gateway net.IP, count int) (addresses []net.IP, err error) {
+	network := binary.BigEndian.Uint32(pool.IP.To4())
+	broadcast := network | ^binary.BigEndian.Uint32(net.IP(pool.Mask).To4())
+	next := binary.BigEndian.Uint32(first)
+	if next == network {
+		next++
+	}
+	for ; len(addresses) < count && next < broadcast; next++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, next)
+		if bytes.Equal(ip, gateway) {
+			continue
+		}
+		addresses = append(addresses, ip)
+	}
+	if len(addresses) < count {
+		err = ErrPoolExhausted
+	}
+	return
+}
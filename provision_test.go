@@ -0,0 +1,87 @@
+package ch912x_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+func TestProvision(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 3)
+	missing := net.HardwareAddr{0x02, 0x91, 0x21, 0xff, 0xff, 0xff}
+	results, err := plane.Provision(context.Background(), ch912x.ProvisionOptions{
+		Pool:     "192.168.1.100/24",
+		Gateway:  net.IPv4(192, 168, 1, 101),
+		Name:     "line-{n}",
+		Modules:  []net.HardwareAddr{addresses[2], missing, addresses[0], addresses[1]},
+		Discover: ch912x.DiscoverOptions{Duration: 200 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the addresses follow the MAC order and skip the gateway, the missing modules come last
+	want := []struct {
+		address net.HardwareAddr
+		ip      net.IP
+		name    string
+	}{
+		{addresses[0], net.IPv4(192, 168, 1, 100), "line-1"},
+		{addresses[1], net.IPv4(192, 168, 1, 102), "line-2"},
+		{addresses[2], net.IPv4(192, 168, 1, 103), "line-3"},
+	}
+	if len(results) != len(want)+1 {
+		t.Fatalf("got %d results, want %d", len(results), len(want)+1)
+	}
+	for i, w := range want {
+		result := results[i]
+		if result.MAC.String() != w.address.String() || !result.IP.Equal(w.ip) || result.Name != w.name || result.Err != nil {
+			t.Errorf("got %s %s %q %v, want %s %s %q", result.MAC, result.IP, result.Name, result.Err, w.address, w.ip, w.name)
+			continue
+		}
+		module, err := plane.Pull(context.Background(), ch912x.ProductCH9121, w.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options := module.Options()
+		if module.Name() != w.name || !options.IP.Equal(w.ip) || !options.Gateway.Equal(net.IPv4(192, 168, 1, 101)) || !options.Mask.Equal(net.IPv4(255, 255, 255, 0)) {
+			t.Errorf("%s is %q %+v", w.address, module.Name(), options)
+		}
+		if reboots := network.Device(w.address).Reboots(); reboots != 1 {
+			t.Errorf("%s rebooted %d times, want 1", w.address, reboots)
+		}
+	}
+	if last := results[len(want)]; last.MAC.String() != missing.String() || last.Err != ch912x.ErrModuleNotFound {
+		t.Errorf("got %s %v, want %s %v", last.MAC, last.Err, missing, ch912x.ErrModuleNotFound)
+	}
+	data, err := json.Marshal(results[len(want)])
+	if err != nil {
+		t.Fatal(err)
+	} else if string(data) != `{"mac":"02:91:21:ff:ff:ff","error":"`+ch912x.ErrModuleNotFound.Error()+`"}` {
+		t.Errorf("got %s", data)
+	}
+}
+
+// TestProvisionPoolExhausted checks the pool before anything is pushed.
+func TestProvisionPoolExhausted(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 3)
+	options := ch912x.ProvisionOptions{
+		Pool:     "192.168.1.253/24",
+		Discover: ch912x.DiscoverOptions{Duration: 200 * time.Millisecond},
+	}
+	if _, err := plane.Provision(context.Background(), options); err != ch912x.ErrPoolExhausted {
+		t.Errorf("got %v, want %v", err, ch912x.ErrPoolExhausted)
+	}
+	for _, address := range addresses {
+		if reboots := network.Device(address).Reboots(); reboots != 0 {
+			t.Errorf("%s rebooted %d times, want 0", address, reboots)
+		}
+	}
+	options.Pool = "fe80::/64"
+	if _, err := plane.Provision(context.Background(), options); err != ch912x.ErrInvalidPool {
+		t.Errorf("got %v, want %v", err, ch912x.ErrInvalidPool)
+	}
+}
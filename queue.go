@@ -0,0 +1,64 @@
+package ch912x
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// moduleQueue runs the operations of the same module one by one in FIFO order,
+// the head of each queue is the running operation.
+type moduleQueue struct {
+	mutex   sync.Mutex
+	waiters map[string][]chan struct{}
+}
+
+func newModuleQueue() *moduleQueue {
+	return &moduleQueue{waiters: make(map[string][]chan struct{})}
+}
+
+func (q *moduleQueue) acquire(ctx context.Context, address net.HardwareAddr) (release func(), err error) {
+	key := address.String()
+	ticket := make(chan struct{})
+	q.mutex.Lock()
+	q.waiters[key] = append(q.waiters[key], ticket)
+	if len(q.waiters[key]) == 1 {
+		close(ticket)
+	}
+	q.mutex.Unlock()
+	select {
+	case <-ticket:
+		release = func() { q.release(key, ticket) }
+	case <-ctx.Done():
+		q.release(key, ticket)
+		err = ctx.Err()
+	}
+	return
+}
+
+func (q *moduleQueue) release(key string, ticket chan struct{}) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	waiters := q.waiters[key]
+	for index, waiter := range waiters {
+		if waiter != ticket {
+			continue
+		}
+		waiters = append(waiters[:index:index], waiters[index+1:]...)
+		if index == 0 && len(waiters) > 0 {
+			close(waiters[0])
+		}
+		break
+	}
+	if len(waiters) == 0 {
+		delete(q.waiters, key)
+	} else {
+		q.waiters[key] = waiters
+	}
+}
+
+func (q *moduleQueue) depth(address net.HardwareAddr) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.waiters[address.String()])
+}
@@ -0,0 +1,88 @@
+package ch912x
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+var queueMAC = net.HardwareAddr{0x02, 0x91, 0x21, 0x00, 0x00, 0x01}
+
+func TestModuleQueueFIFO(t *testing.T) {
+	q := newModuleQueue()
+	release, err := q.acquire(context.Background(), queueMAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan int, 8)
+	for i := 0; i < cap(order); i++ {
+		go func(i int) {
+			release, err := q.acquire(context.Background(), queueMAC)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		// queued one by one so the tickets are taken in the order of i
+		waitDepth(t, q, i+2)
+	}
+	release()
+	for i := 0; i < cap(order); i++ {
+		if got := <-order; got != i {
+			t.Fatalf("operation %d ran in place of %d", got, i)
+		}
+	}
+	waitDepth(t, q, 0)
+}
+
+func TestModuleQueueCancel(t *testing.T) {
+	q := newModuleQueue()
+	release, err := q.acquire(context.Background(), queueMAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := q.acquire(ctx, queueMAC)
+		canceled <- err
+	}()
+	waitDepth(t, q, 2)
+	next := make(chan struct{})
+	go func() {
+		release, err := q.acquire(context.Background(), queueMAC)
+		if err != nil {
+			t.Error(err)
+		} else {
+			release()
+		}
+		close(next)
+	}()
+	waitDepth(t, q, 3)
+	cancel()
+	if err := <-canceled; err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	waitDepth(t, q, 2)
+	release()
+	select {
+	case <-next:
+	case <-time.After(time.Second):
+		t.Fatal("the ticket behind the canceled one was not granted")
+	}
+	waitDepth(t, q, 0)
+}
+
+func waitDepth(t *testing.T, q *moduleQueue, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.depth(queueMAC) != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth %d, want %d", q.depth(queueMAC), depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,157 @@
+package ch912x
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// registry correlates the in-flight requests with the responses and the ARP announcements.
+// An entry lives from register/watch until its release, the response goroutines never block on it.
+type registry struct {
+	mutex    sync.Mutex
+	requests map[string]chan Module
+	arpTable map[string]map[*pendingARP]struct{}
+	ipTable  map[string]map[chan net.HardwareAddr]struct{}
+}
+
+type pendingRequest struct {
+	registry *registry
+	key      string
+	returns  chan Module
+}
+
+// pendingARP is done once the announcement is seen, it stays done for the later waits.
+type pendingARP struct {
+	registry  *registry
+	key       string
+	done      chan struct{}
+	announced bool
+}
+
+type pendingProbe struct {
+	registry *registry
+	key      string
+	returns  chan net.HardwareAddr
+}
+
+func newRegistry() *registry {
+	return &registry{
+		requests: make(map[string]chan Module),
+		arpTable: make(map[string]map[*pendingARP]struct{}),
+		ipTable:  make(map[string]map[chan net.HardwareAddr]struct{}),
+	}
+}
+
+func requestKey(kind Kind, address net.HardwareAddr) string {
+	return string([]byte{byte(kind | 0x80)}) + address.String()
+}
+
+func (r *registry) register(kind Kind, address net.HardwareAddr) (*pendingRequest, error) {
+	key := requestKey(kind, address)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.requests[key]; ok {
+		return nil, ErrTaskRunning
+	}
+	returns := make(chan Module, 1)
+	r.requests[key] = returns
+	return &pendingRequest{registry: r, key: key, returns: returns}, nil
+}
+
+func (r *registry) resolve(kind Kind, address net.HardwareAddr, module Module) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	returns, ok := r.requests[requestKey(kind, address)]
+	if !ok {
+		return false
+	}
+	select {
+	case returns <- module:
+	default:
+	}
+	return true
+}
+
+func (r *registry) watchARP(address net.HardwareAddr) *pendingARP {
+	pending := &pendingARP{registry: r, key: address.String(), done: make(chan struct{})}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.arpTable[pending.key] == nil {
+		r.arpTable[pending.key] = make(map[*pendingARP]struct{})
+	}
+	r.arpTable[pending.key][pending] = struct{}{}
+	return pending
+}
+
+func (r *registry) announce(address net.HardwareAddr) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for pending := range r.arpTable[address.String()] {
+		if !pending.announced {
+			pending.announced = true
+			close(pending.done)
+		}
+	}
+}
+
+func (r *registry) watchIP(ip net.IP) *pendingProbe {
+	key := ip.String()
+	returns := make(chan net.HardwareAddr, 1)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.ipTable[key] == nil {
+		r.ipTable[key] = make(map[chan net.HardwareAddr]struct{})
+	}
+	r.ipTable[key][returns] = struct{}{}
+	return &pendingProbe{registry: r, key: key, returns: returns}
+}
+
+func (r *registry) claim(sender net.HardwareAddr, ip net.IP) {
+	if ip == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for returns := range r.ipTable[ip.String()] {
+		select {
+		case returns <- sender:
+		default:
+		}
+	}
+}
+
+func (p *pendingRequest) release() {
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	if p.registry.requests[p.key] == p.returns {
+		delete(p.registry.requests, p.key)
+	}
+}
+
+func (p *pendingARP) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return nil
+	}
+}
+
+func (p *pendingARP) release() {
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	delete(p.registry.arpTable[p.key], p)
+	if len(p.registry.arpTable[p.key]) == 0 {
+		delete(p.registry.arpTable, p.key)
+	}
+}
+
+func (p *pendingProbe) release() {
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	delete(p.registry.ipTable[p.key], p.returns)
+	if len(p.registry.ipTable[p.key]) == 0 {
+		delete(p.registry.ipTable, p.key)
+	}
+}
@@ -0,0 +1,131 @@
+package ch912x
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+type SafePushStage string
+
+const (
+	StageSnapshot SafePushStage = "snapshot"
+	StageProbe    SafePushStage = "probe"
+	StagePush     SafePushStage = "push"
+	StageVerify   SafePushStage = "verify"
+	StageRollback SafePushStage = "rollback"
+)
+
+type SafePushStep struct {
+	Stage    SafePushStage
+	Duration time.Duration
+	Err      error
+}
+
+// SafePushReport describes a SafePush, Current is the configuration pulled back,
+// Changes lists the fields the module did not take and RolledBackTo is the answer to the rollback.
+type SafePushReport struct {
+	Previous     Module
+	Current      Module
+	Changes      []Change
+	RolledBack   bool
+	RolledBackTo Module
+	Steps        []*SafePushStep
+}
+
+func (r *SafePushReport) run(stage SafePushStage, fn func() error) error {
+	started := time.Now()
+	err := fn()
+	r.Steps = append(r.Steps, &SafePushStep{Stage: stage, Duration: time.Since(started), Err: err})
+	return err
+}
+
+// SafePush snapshots the configuration, pushes the module, then pulls it back and compares.
+// When the module does not come back within Timeout or the configuration does not match,
+// the snapshot is pushed back to restore the module.
+func (p *ControlPlane) SafePush(ctx context.Context, module Module) (report *SafePushReport, err error) {
+	if err = checkPushRequest(module); err != nil {
+		return
+	}
+	product := module.Product()
+	address := module.MAC()
+	release, err := p.queue.acquire(ctx, address)
+	if err != nil {
+		return
+	}
+	defer release()
+	report = new(SafePushReport)
+	err = report.run(StageSnapshot, func() (err error) {
+		ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+		report.Previous, err = p.pull(ctx, product, address)
+		return
+	})
+	if err != nil {
+		return
+	}
+	expected, err := reencode(module)
+	if err != nil {
+		return
+	}
+	err = report.run(StageProbe, func() error {
+		return p.detectConflict(ctx, module)
+	})
+	if err != nil {
+		return
+	}
+	err = report.run(StagePush, func() (err error) {
+		_, offline, err := p.apply(ctx, module)
+		if err == nil && offline {
+			err = ErrModuleOffline
+		}
+		return
+	})
+	if err == nil {
+		err = report.run(StageVerify, func() (err error) {
+			ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+			defer cancel()
+			if report.Current, err = p.pull(ctx, product, address); err != nil {
+				return
+			}
+			if report.Changes, err = Diff(expected, report.Current); err == nil && len(report.Changes) > 0 {
+				err = ErrPushNotApplied
+			}
+			return
+		})
+	}
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	_ = report.run(StageRollback, func() (err error) {
+		previous, err := MergePatch(report.Previous, []byte("{}"))
+		if err != nil {
+			return
+		}
+		var offline bool
+		report.RolledBackTo, offline, err = p.apply(ctx, previous)
+		if err == nil && offline {
+			err = ErrModuleOffline
+		}
+		report.RolledBack = err == nil
+		return
+	})
+	return
+}
+
+// reencode reads the module back from its wire format, the fields left out are filled
+// the way the module stores them.
+func reencode(module Module) (decoded Module, err error) {
+	descriptor := LookupProduct(module.Product())
+	if descriptor == nil {
+		err = ErrUnknownModuleType
+		return
+	}
+	var buf bytes.Buffer
+	if _, err = module.WriteTo(&buf); err != nil {
+		return
+	}
+	decoded = descriptor.New()
+	_, err = decoded.ReadFrom(&buf)
+	return
+}
@@ -0,0 +1,126 @@
+package ch912x_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+	"github.com/CursedHardware/ch912x/ch912xsim"
+)
+
+// arpFilter drops the announcements once silenced, the modules are never seen back online.
+type arpFilter struct {
+	*ch912xsim.ARP
+	silent int32
+}
+
+func (f *arpFilter) ReadARP() (sender net.HardwareAddr, senderIP, targetIP net.IP, err error) {
+	for {
+		if sender, senderIP, targetIP, err = f.ARP.ReadARP(); err != nil || atomic.LoadInt32(&f.silent) == 0 {
+			return
+		}
+	}
+}
+
+func safePush(t *testing.T, plane *ch912x.ControlPlane, address net.HardwareAddr, name string) (*ch912x.SafePushReport, error) {
+	t.Helper()
+	current, err := plane.Pull(context.Background(), ch912x.ProductCH9121, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := ch912x.MergePatch(current, []byte(`{"module_name":"`+name+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plane.SafePush(context.Background(), module)
+}
+
+func checkStages(t *testing.T, report *ch912x.SafePushReport, stages ...ch912x.SafePushStage) {
+	t.Helper()
+	if len(report.Steps) != len(stages) {
+		t.Fatalf("%d steps, want %v", len(report.Steps), stages)
+	}
+	for i, step := range report.Steps {
+		if step.Stage != stages[i] {
+			t.Errorf("step %d is %s, want %s", i, step.Stage, stages[i])
+		}
+	}
+}
+
+func TestSafePushVerified(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 1)
+	report, err := safePush(t, plane, addresses[0], "verified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStages(t, report, ch912x.StageSnapshot, ch912x.StageProbe, ch912x.StagePush, ch912x.StageVerify)
+	if name := report.Current.Name(); name != "verified" {
+		t.Errorf("pulled back %q", name)
+	} else if report.RolledBack || len(report.Changes) > 0 {
+		t.Errorf("rolled back %v, changes %v", report.RolledBack, report.Changes)
+	}
+	if name := network.Device(addresses[0]).Module().Name(); name != "verified" {
+		t.Errorf("the module is named %q", name)
+	}
+}
+
+func TestSafePushMismatchRolledBack(t *testing.T) {
+	network, plane, addresses := newNetwork(t, 1)
+	original := network.Device(addresses[0]).Module()
+	// the module is swapped for one with the previous configuration once the push is answered,
+	// the configuration pulled back does not match
+	var once sync.Once
+	plane.OnOperation(func(operation *ch912x.Operation) {
+		if operation.Kind == ch912x.KindPushRequest {
+			once.Do(func() {
+				network.Detach(addresses[0])
+				if _, err := network.Attach(original); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	})
+	report, err := safePush(t, plane, addresses[0], "mismatch")
+	if !errors.Is(err, ch912x.ErrPushNotApplied) {
+		t.Fatalf("got %v, want %v", err, ch912x.ErrPushNotApplied)
+	}
+	checkStages(t, report, ch912x.StageSnapshot, ch912x.StageProbe, ch912x.StagePush, ch912x.StageVerify, ch912x.StageRollback)
+	if len(report.Changes) != 1 || report.Changes[0].Path != "module_name" {
+		t.Errorf("got the changes %v", report.Changes)
+	}
+	if !report.RolledBack || report.Steps[4].Err != nil {
+		t.Errorf("not rolled back: %v", report.Steps[4].Err)
+	}
+	if reboots := network.Device(addresses[0]).Reboots(); reboots != 1 {
+		t.Errorf("the module rebooted %d times for the rollback, want 1", reboots)
+	}
+}
+
+func TestSafePushRollbackOffline(t *testing.T) {
+	network, _, addresses := newNetwork(t, 1)
+	arp := &arpFilter{ARP: network.ListenARP()}
+	plane := ch912x.NewControlPlane(network.Listen(), arp, clientMAC)
+	configure(plane)
+	plane.Timeout = time.Second
+	defer plane.Close()
+	plane.OnOperation(func(operation *ch912x.Operation) {
+		if operation.Kind == ch912x.KindPushRequest {
+			atomic.StoreInt32(&arp.silent, 1)
+		}
+	})
+	report, err := safePush(t, plane, addresses[0], "offline")
+	if !errors.Is(err, ch912x.ErrModuleOffline) {
+		t.Fatalf("got %v, want %v", err, ch912x.ErrModuleOffline)
+	}
+	checkStages(t, report, ch912x.StageSnapshot, ch912x.StageProbe, ch912x.StagePush, ch912x.StageRollback)
+	if report.RolledBack {
+		t.Error("rolled back while the module was not seen back online")
+	} else if err := report.Steps[3].Err; !errors.Is(err, ch912x.ErrModuleOffline) {
+		t.Errorf("the rollback failed with %v, want %v", err, ch912x.ErrModuleOffline)
+	}
+}
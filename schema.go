@@ -0,0 +1,157 @@
+package ch912x
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var schemaEnums = map[reflect.Type][]interface{}{
+	reflect.TypeOf(UARTMode(0)):   {TCPServer, TCPClient, UDPServer, UDPClient},
+	reflect.TypeOf(UARTParity(0)): {ParityNone, ParityEven, ParityOdd, ParityMark, ParitySpace},
+	reflect.TypeOf(NTPMode(0)):    {NTPServer, NTPClient},
+}
+
+// JSONSchema generates the JSON Schema (draft 2020-12) of the JSON document of the product running the firmware version,
+// the ranges and the lengths follow its capabilities and the unsupported fields only accept their zero value.
+func JSONSchema(product Product, version string) (schema map[string]interface{}, err error) {
+	descriptor := LookupProduct(product)
+	if descriptor == nil {
+		err = ErrUnknownModuleType
+		return
+	}
+	schema = schemaOf(reflect.TypeOf(descriptor.New()))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = string(descriptor.Product)
+	schema["properties"].(map[string]interface{})["product"] = map[string]interface{}{"const": descriptor.Product}
+	schema["required"] = []string{"product"}
+	constrainSchema(schema, descriptor.Capabilities(version))
+	return
+}
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if values, ok := schemaEnums[t]; ok {
+		return enumSchema(values)
+	}
+	switch t {
+	case reflect.TypeOf(net.IP{}):
+		// an unset address is written as the empty string
+		return map[string]interface{}{"type": "string", "anyOf": []interface{}{
+			map[string]interface{}{"format": "ipv4"},
+			map[string]interface{}{"maxLength": 0},
+		}}
+	case reflect.TypeOf(net.HardwareAddr{}):
+		return map[string]interface{}{"type": "string", "pattern": "^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "minimum": 0, "maximum": uint64(math.MaxUint64) >> (64 - t.Bits())}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.PkgPath != "" || name == "-" || name == "" {
+				continue
+			}
+			properties[name] = schemaOf(field.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+	return map[string]interface{}{}
+}
+
+// enumSchema takes the names of the values, the numbers written before the names are deprecated.
+func enumSchema(values []interface{}) map[string]interface{} {
+	names := make([]interface{}, len(values))
+	numbers := make([]interface{}, len(values))
+	for i, value := range values {
+		names[i] = value.(fmt.Stringer).String()
+		numbers[i] = reflect.ValueOf(value).Uint()
+	}
+	return map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"type": "string", "enum": names},
+		map[string]interface{}{"type": "integer", "enum": numbers, "deprecated": true},
+	}}
+}
+
+func constrainSchema(schema map[string]interface{}, caps *Capabilities) {
+	set := func(path, key string, value interface{}) {
+		if property := schemaProperty(schema, path); property != nil {
+			property[key] = value
+		}
+	}
+	unsupported := func(supported bool, path string, zero interface{}) {
+		if !supported {
+			set(path, "const", zero)
+		}
+	}
+	set("module_name", "maxLength", caps.NameLength)
+	unsupported(caps.DHCP, "module_options.use_dhcp", false)
+	unsupported(caps.SerialNegotiate, "module_options.serial_negotiate", false)
+	unsupported(caps.UARTs > 1 || caps.UARTSwitch, "module_options.enabled_minor_uart", false)
+	for _, uart := range []string{"uart_1", "uart_2"} {
+		if !caps.UARTSwitch {
+			if properties, ok := schemaProperty(schema, uart)["properties"].(map[string]interface{}); ok {
+				properties["enabled"] = false
+			}
+		}
+		set(uart+".client_domain", "maxLength", caps.DomainLength)
+		unsupported(caps.DomainLength > 0, uart+".use_domain", false)
+		unsupported(caps.SocketOptions, uart+".random_client_port", false)
+		unsupported(caps.SocketOptions, uart+".close_on_lost", false)
+		unsupported(caps.SocketOptions, uart+".clear_on_reconnect", false)
+		if caps.MaxBaud == 0 {
+			for _, name := range []string{"baud", "data_bits", "stop_bit", "packet_size", "packet_timeout"} {
+				unsupported(false, uart+"."+name, 0)
+			}
+			unsupported(false, uart+".parity", ParityNone)
+			continue
+		}
+		set(uart+".baud", "minimum", caps.MinBaud)
+		set(uart+".baud", "maximum", caps.MaxBaud)
+		set(uart+".baud", "examples", caps.BaudRates)
+		set(uart+".data_bits", "enum", caps.DataBits)
+		set(uart+".stop_bit", "enum", caps.StopBits)
+		parities := make([]interface{}, len(caps.Parities))
+		for i, parity := range caps.Parities {
+			parities[i] = parity
+		}
+		if properties, ok := schemaProperty(schema, uart)["properties"].(map[string]interface{}); ok {
+			properties["parity"] = enumSchema(parities)
+		}
+		if caps.PacketSize > 0 {
+			set(uart+".packet_size", "maximum", caps.PacketSize)
+		}
+	}
+	unsupported(caps.NTP, "ntp.enabled", false)
+	set("ntp.keep_alive", "deprecated", true)
+	unsupported(caps.KeepAlive, "keep_alive.enabled", false)
+}
+
+func schemaProperty(schema map[string]interface{}, path string) (property map[string]interface{}) {
+	property = schema
+	for _, name := range strings.Split(path, ".") {
+		properties, _ := property["properties"].(map[string]interface{})
+		if property, _ = properties[name].(map[string]interface{}); property == nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,152 @@
+package ch912x_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/CursedHardware/ch912x"
+	"github.com/CursedHardware/ch912x/ch912xsim"
+)
+
+// checkSchema validates the document against the keywords JSONSchema generates,
+// it returns the JSON pointer of the first mismatch.
+func checkSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if expected, ok := schema["const"]; ok && !reflect.DeepEqual(expected, value) {
+		return fmt.Errorf("%s: %v is not %v", path, value, expected)
+	}
+	if values, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, expected := range values {
+			found = found || reflect.DeepEqual(expected, value)
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, values)
+		}
+	}
+	if schemas, ok := schema["anyOf"].([]interface{}); ok {
+		var err error
+		for _, sub := range schemas {
+			if err = checkSchema(sub.(map[string]interface{}), value, path); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if t, ok := schema["type"].(string); ok && t != "object" {
+			return fmt.Errorf("%s: %v is not a %s", path, value, t)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, member := range value {
+			property, ok := properties[name]
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s/%s: not allowed", path, name)
+				}
+				continue
+			} else if property == false {
+				return fmt.Errorf("%s/%s: not allowed", path, name)
+			}
+			if err := checkSchema(property.(map[string]interface{}), member, path+"/"+name); err != nil {
+				return err
+			}
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := value[name.(string)]; !ok {
+					return fmt.Errorf("%s/%s: required", path, name)
+				}
+			}
+		}
+	case string:
+		if t, ok := schema["type"].(string); ok && t != "string" {
+			return fmt.Errorf("%s: %q is not a %s", path, value, t)
+		}
+		if max, ok := schema["maxLength"].(float64); ok && utf8.RuneCountInString(value) > int(max) {
+			return fmt.Errorf("%s: %q is longer than %v", path, value, max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+			return fmt.Errorf("%s: %q does not match %s", path, value, pattern)
+		}
+		if schema["format"] == "ipv4" && net.ParseIP(value).To4() == nil {
+			return fmt.Errorf("%s: %q is not an IPv4 address", path, value)
+		}
+	case float64:
+		if t, ok := schema["type"].(string); ok && (t != "integer" || value != float64(int64(value))) {
+			return fmt.Errorf("%s: %v is not a %s", path, value, t)
+		}
+		if min, ok := schema["minimum"].(float64); ok && value < min {
+			return fmt.Errorf("%s: %v is less than %v", path, value, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && value > max {
+			return fmt.Errorf("%s: %v is greater than %v", path, value, max)
+		}
+	case bool:
+		if t, ok := schema["type"].(string); ok && t != "boolean" {
+			return fmt.Errorf("%s: %v is not a %s", path, value, t)
+		}
+	}
+	return nil
+}
+
+// toJSON reads the value back as encoding/json decodes any document.
+func toJSON(t *testing.T, value interface{}) (decoded interface{}) {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestSchemaAcceptsModules(t *testing.T) {
+	netModule, err := ch912x.MergePatch(
+		ch912x.LookupProduct(ch912x.ProductNetModule).Request(ch912x.KindPushRequest, testMAC),
+		[]byte(`{"module_name":"NET_MODULE","module_options":{"ip":"192.168.1.200"},"uart_1":{"baud":9600,"data_bits":8,"stop_bit":1,"parity":"odd"}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, module := range []ch912x.Module{
+		ch912xsim.NewCH9120(testMAC),
+		ch912xsim.NewCH9121(testMAC),
+		ch912xsim.NewCH9126(testMAC),
+		netModule,
+	} {
+		generated, err := ch912x.JSONSchema(module.Product(), module.Version())
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema := toJSON(t, generated).(map[string]interface{})
+		document := toJSON(t, module).(map[string]interface{})
+		if err = checkSchema(schema, document, ""); err != nil {
+			t.Errorf("%s: %v", module.Product(), err)
+		}
+		// the schema does not accept anything either
+		document["module_name"] = "0123456789012345678901234567890123456789012345678901234567890123456789"
+		if checkSchema(schema, document, "") == nil {
+			t.Errorf("%s: the schema accepts a name of 70 characters", module.Product())
+		}
+		delete(document, "module_name")
+		document["module_options"].(map[string]interface{})["ip"] = "fe80::1"
+		if checkSchema(schema, document, "") == nil {
+			t.Errorf("%s: the schema accepts an IPv6 address", module.Product())
+		}
+		delete(document, "module_options")
+		document["unknown"] = true
+		if checkSchema(schema, document, "") == nil {
+			t.Errorf("%s: the schema accepts an unknown member", module.Product())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package ch912x
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type DropPolicy byte
+
+const (
+	DropNewest DropPolicy = iota
+	DropOldest
+)
+
+type SubscribeOptions struct {
+	Buffer int
+	Policy DropPolicy
+}
+
+// Subscription receives the discovered modules until it is unsubscribed,
+// its context is done or the control plane is closed, then C is closed.
+type Subscription struct {
+	C       <-chan Module
+	modules chan Module
+	filter  func(Module) bool
+	policy  DropPolicy
+	hub     *discoveryHub
+	done    chan struct{}
+	dropped uint64
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.hub.remove(s)
+}
+
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) deliver(module Module) {
+	if s.filter != nil && !s.filter(module) {
+		return
+	}
+	for {
+		select {
+		case s.modules <- module:
+			return
+		default:
+		}
+		atomic.AddUint64(&s.dropped, 1)
+		if s.policy != DropOldest {
+			return
+		}
+		select {
+		case <-s.modules:
+		default:
+		}
+	}
+}
+
+type discoveryHub struct {
+	mutex       sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func newDiscoveryHub() *discoveryHub {
+	return &discoveryHub{subscribers: make(map[*Subscription]struct{})}
+}
+
+func (h *discoveryHub) add(ctx context.Context, filter func(Module) bool, options SubscribeOptions) *Subscription {
+	if options.Buffer <= 0 {
+		options.Buffer = 1
+	}
+	modules := make(chan Module, options.Buffer)
+	s := &Subscription{
+		C:       modules,
+		modules: modules,
+		filter:  filter,
+		policy:  options.Policy,
+		hub:     h,
+		done:    make(chan struct{}),
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		close(s.done)
+		close(s.modules)
+		return s
+	}
+	h.subscribers[s] = struct{}{}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Unsubscribe()
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+func (h *discoveryHub) remove(s *Subscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+	delete(h.subscribers, s)
+	close(s.done)
+	close(s.modules)
+}
+
+func (h *discoveryHub) publish(module Module) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for s := range h.subscribers {
+		s.deliver(module)
+	}
+}
+
+func (h *discoveryHub) close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.closed = true
+	for s := range h.subscribers {
+		delete(h.subscribers, s)
+		close(s.done)
+		close(s.modules)
+	}
+}
+
+// Subscribe fans out the discovered modules matching the filter (nil for all) with a buffer of 16,
+// the oldest module is dropped when the subscriber falls behind.
+func (p *ControlPlane) Subscribe(ctx context.Context, filter func(Module) bool) *Subscription {
+	return p.SubscribeWithOptions(ctx, filter, SubscribeOptions{Buffer: 16, Policy: DropOldest})
+}
+
+func (p *ControlPlane) SubscribeWithOptions(ctx context.Context, filter func(Module) bool, options SubscribeOptions) *Subscription {
+	return p.discovery.add(ctx, filter, options)
+}
+
+func (p *ControlPlane) Discovery() <-chan Module {
+	p.discoveryOnce.Do(func() {
+		p.discoveryLegacy = p.Subscribe(context.Background(), nil)
+	})
+	return p.discoveryLegacy.C
+}
@@ -0,0 +1,109 @@
+package ch912x_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CursedHardware/ch912x"
+)
+
+func receive(t *testing.T, s *ch912x.Subscription, count int) map[string]bool {
+	t.Helper()
+	seen := make(map[string]bool)
+	timeout := time.After(time.Second)
+	for len(seen) < count {
+		select {
+		case module, ok := <-s.C:
+			if !ok {
+				t.Fatalf("closed after %d modules, want %d", len(seen), count)
+			}
+			seen[module.MAC().String()] = true
+		case <-timeout:
+			t.Fatalf("received %d modules, want %d", len(seen), count)
+		}
+	}
+	return seen
+}
+
+func waitClosed(t *testing.T, s *ch912x.Subscription) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-s.C:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("the subscription was not closed")
+		}
+	}
+}
+
+func TestSubscribeFanOut(t *testing.T) {
+	_, plane, addresses := newNetwork(t, 8)
+	first := plane.Subscribe(context.Background(), nil)
+	second := plane.Subscribe(context.Background(), nil)
+	filtered := plane.Subscribe(context.Background(), func(module ch912x.Module) bool {
+		return module.MAC().String() == addresses[0].String()
+	})
+	if err := plane.SendDiscovery(ch912x.ProductCH9121); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*ch912x.Subscription{first, second} {
+		seen := receive(t, s, len(addresses))
+		for _, address := range addresses {
+			if !seen[address.String()] {
+				t.Errorf("%s was not received", address)
+			}
+		}
+	}
+	receive(t, filtered, 1)
+	select {
+	case module := <-filtered.C:
+		t.Errorf("the filter let %s through", module.MAC())
+	case <-time.After(50 * time.Millisecond):
+	}
+	for _, s := range []*ch912x.Subscription{first, second, filtered} {
+		if dropped := s.Dropped(); dropped != 0 {
+			t.Errorf("%d modules dropped", dropped)
+		}
+	}
+}
+
+func TestSubscribeDropOldest(t *testing.T) {
+	_, plane, addresses := newNetwork(t, 4)
+	s := plane.SubscribeWithOptions(context.Background(), nil, ch912x.SubscribeOptions{Buffer: 1, Policy: ch912x.DropOldest})
+	if err := plane.SendDiscovery(ch912x.ProductCH9121); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for s.Dropped() < uint64(len(addresses)-1) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d modules dropped, want %d", s.Dropped(), len(addresses)-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := <-s.C; !ok {
+		t.Fatal("the newest module was dropped as well")
+	}
+}
+
+func TestSubscribeClose(t *testing.T) {
+	_, plane, _ := newNetwork(t, 1)
+	unsubscribed := plane.Subscribe(context.Background(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := plane.Subscribe(ctx, nil)
+	closed := plane.Subscribe(context.Background(), nil)
+	unsubscribed.Unsubscribe()
+	unsubscribed.Unsubscribe()
+	waitClosed(t, unsubscribed)
+	cancel()
+	waitClosed(t, canceled)
+	if err := plane.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitClosed(t, closed)
+	waitClosed(t, plane.Subscribe(context.Background(), nil))
+}
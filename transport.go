@@ -0,0 +1,77 @@
+package ch912x
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/mdlayher/arp"
+)
+
+type UDPTransport interface {
+	ReadFrom(p []byte) (n int, addr net.Addr, err error)
+	WriteTo(p []byte, addr net.Addr) (n int, err error)
+	Close() error
+}
+
+// ARPTransport reads the ARP packets seen on the link, the RFC 5227 probes carry
+// the unspecified senderIP and the probed address as targetIP.
+type ARPTransport interface {
+	ReadARP() (sender net.HardwareAddr, senderIP, targetIP net.IP, err error)
+	Close() error
+}
+
+// ARPProber is implemented by the ARP transports able to send the RFC 5227 probes,
+// the replies are read back through ReadARP.
+type ARPProber interface {
+	ProbeARP(ip net.IP) error
+}
+
+type arpTransport struct {
+	*arp.Client
+	hardwareAddr net.HardwareAddr
+}
+
+func DialARP(ifi *net.Interface) (ARPTransport, error) {
+	client, err := arp.Dial(ifi)
+	if err != nil {
+		return nil, err
+	}
+	return &arpTransport{client, ifi.HardwareAddr}, nil
+}
+
+func (t *arpTransport) ReadARP() (sender net.HardwareAddr, senderIP, targetIP net.IP, err error) {
+	packet, _, err := t.Read()
+	if err != nil {
+		return
+	}
+	return packet.SenderHardwareAddr, packet.SenderIP.AsSlice(), packet.TargetIP.AsSlice(), nil
+}
+
+func (t *arpTransport) ProbeARP(ip net.IP) (err error) {
+	target, ok := netip.AddrFromSlice(ip.To4())
+	if !ok {
+		return ErrInvalidProbeAddress
+	}
+	broadcast := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	packet, err := arp.NewPacket(arp.OperationRequest, t.hardwareAddr, netip.IPv4Unspecified(), make(net.HardwareAddr, 6), target)
+	if err != nil {
+		return
+	}
+	return t.WriteTo(packet, broadcast)
+}
+
+func ListenUDP(ifi *net.Interface) (conn *net.UDPConn, err error) {
+	if ifi == nil {
+		err = ErrInvalidNetworkInterface
+		return
+	}
+	conn, err = net.ListenUDP("udp", &net.UDPAddr{Port: listenPort})
+	if err != nil {
+		return
+	}
+	if err = bindInterfaceToUDPConn(conn, ifi); err != nil {
+		_ = conn.Close()
+		conn = nil
+	}
+	return
+}
@@ -1,18 +1,35 @@
 package ch912x
 
 import (
+	"encoding/json"
 	"io"
 	"net"
 )
 
+// Module is a configuration packet of a product, Kind tells the request or the response it is.
 type Module interface {
-	identity() (Kind, net.HardwareAddr)
-	moduleIP() net.IP
-	setClientMAC(net.HardwareAddr)
+	Product() Product
+	Kind() Kind
+	MAC() net.HardwareAddr
+	IP() net.IP
+	Name() string
+	Version() string
+	Options() *ModuleOptions
+	ClientAddress() net.HardwareAddr
+	Clone() Module
+	Capabilities() *Capabilities
+	SetKind(Kind)
+	SetVersion(string)
+	SetClientMAC(net.HardwareAddr)
+	Validate() error
 	io.ReaderFrom
 	io.WriterTo
 }
 
+func cloneBytes(data []byte) []byte {
+	return append([]byte(nil), data...)
+}
+
 type ModuleOptions struct {
 	MAC              net.HardwareAddr `json:"mac,omitempty"`
 	IP               net.IP           `json:"ip,omitempty"`
@@ -23,7 +40,42 @@ type ModuleOptions struct {
 	EnabledMinorUART bool             `json:"enabled_minor_uart,omitempty"`
 }
 
+func (o ModuleOptions) MarshalJSON() ([]byte, error) {
+	type Options ModuleOptions
+	options := new(struct {
+		MAC macAddress `json:"mac,omitempty"`
+		*Options
+	})
+	options.MAC = macAddress(o.MAC)
+	options.Options = (*Options)(&o)
+	return json.Marshal(options)
+}
+
+func (o *ModuleOptions) UnmarshalJSON(data []byte) error {
+	type Options ModuleOptions
+	options := new(struct {
+		MAC *macAddress `json:"mac,omitempty"`
+		*Options
+	})
+	options.MAC = (*macAddress)(&o.MAC)
+	options.Options = (*Options)(o)
+	return json.Unmarshal(data, options)
+}
+
+func (o *ModuleOptions) clone() *ModuleOptions {
+	if o == nil {
+		return nil
+	}
+	cloned := *o
+	cloned.MAC = cloneBytes(o.MAC)
+	cloned.IP = cloneBytes(o.IP)
+	cloned.Mask = cloneBytes(o.Mask)
+	cloned.Gateway = cloneBytes(o.Gateway)
+	return &cloned
+}
+
 type UARTService struct {
+	Enabled          *bool      `json:"enabled,omitempty"`
 	Mode             UARTMode   `json:"mode"`
 	ClientIP         net.IP     `json:"client_ip"`
 	ClientPort       uint16     `json:"client_port"`
@@ -41,11 +93,31 @@ type UARTService struct {
 	Parity           UARTParity `json:"parity"`
 }
 
+func (s *UARTService) clone() *UARTService {
+	if s == nil {
+		return nil
+	}
+	cloned := *s
+	if s.Enabled != nil {
+		enabled := *s.Enabled
+		cloned.Enabled = &enabled
+	}
+	cloned.ClientIP = cloneBytes(s.ClientIP)
+	return &cloned
+}
+
 type NTPService struct {
 	Enabled     bool    `json:"enabled"`
 	Mode        NTPMode `json:"mode"`
 	ClientIP    net.IP  `json:"client_ip"`
 	Polling     uint16  `json:"polling"`
 	PulseOutput bool    `json:"pulse_output"`
-	KeepAlive   bool    `json:"keep_alive"`
+	KeepAlive   bool    `json:"keep_alive,omitempty"` // Deprecated: use CH9126.KeepAlive
+}
+
+type KeepAlive struct {
+	Enabled  bool   `json:"enabled"`
+	Time     uint32 `json:"time"`
+	Interval uint32 `json:"interval"`
+	Probes   uint32 `json:"probes"`
 }
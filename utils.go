@@ -1,19 +1,76 @@
 package ch912x
 
 import (
+	"bytes"
+	"encoding/binary"
 	"net"
+	"reflect"
 	"strings"
-	"syscall"
 )
 
 func trimNull(values []byte) string {
 	return strings.TrimRight(string(values), "\x00")
 }
 
+func setString(dst []byte, s string) {
+	if trimNull(dst) == s {
+		return
+	}
+	for i := range dst {
+		dst[i] = 0
+	}
+	copy(dst, s)
+}
+
+func copyIP(dst []byte, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		copy(dst, ip4)
+	} else {
+		copy(dst, ip)
+	}
+}
+
+// encodeWithRaw encodes the layout, keeping the bytes of the blank `_` fields
+// and the bytes following the layout from raw.
+func encodeWithRaw(layout interface{}, raw []byte) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, layout)
+	data := buf.Bytes()
+	if len(raw) == 0 {
+		return data
+	}
+	restoreBlankFields(data, raw, reflect.Indirect(reflect.ValueOf(layout)).Type(), 0)
+	if len(raw) > len(data) {
+		data = append(data, raw[len(data):]...)
+	}
+	return data
+}
+
+func restoreBlankFields(data, raw []byte, t reflect.Type, offset int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		size := binary.Size(reflect.Zero(field.Type).Interface())
+		if field.Name == "_" {
+			if end := offset + size; end <= len(data) && end <= len(raw) {
+				copy(data[offset:end], raw[offset:end])
+			}
+		} else if field.Type.Kind() == reflect.Struct {
+			restoreBlankFields(data, raw, field.Type, offset)
+		}
+		offset += size
+	}
+}
+
 func fromVariantBool(x byte) bool {
 	return x != 0
 }
 
+func setVariantBool(dst *byte, x bool) {
+	if fromVariantBool(*dst) != x {
+		*dst = toVariantBool(x)
+	}
+}
+
 func toVariantBool(x bool) byte {
 	if x {
 		return 1
@@ -26,8 +83,11 @@ func bindInterfaceToUDPConn(conn *net.UDPConn, ifi *net.Interface) (err error) {
 	if err != nil {
 		return
 	}
-	return raw.Control(func(fd uintptr) {
-		// see https://stackoverflow.com/a/57013928
-		_ = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.IP_BOUND_IF, ifi.Index)
+	control := raw.Control(func(fd uintptr) {
+		err = bindInterface(fd, ifi)
 	})
+	if control != nil {
+		err = control
+	}
+	return
 }
@@ -0,0 +1,11 @@
+package ch912x
+
+import (
+	"net"
+	"syscall"
+)
+
+func bindInterface(fd uintptr, ifi *net.Interface) error {
+	// see https://stackoverflow.com/a/57013928
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_BOUND_IF, ifi.Index)
+}
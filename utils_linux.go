@@ -0,0 +1,10 @@
+package ch912x
+
+import (
+	"net"
+	"syscall"
+)
+
+func bindInterface(fd uintptr, ifi *net.Interface) error {
+	return syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, ifi.Name)
+}
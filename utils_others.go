@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package ch912x
+
+import "net"
+
+func bindInterface(fd uintptr, ifi *net.Interface) error {
+	return ErrBindInterfaceUnsupported
+}
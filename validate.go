@@ -0,0 +1,194 @@
+package ch912x
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("ch912x: %s: %s", e.Field, e.Reason)
+}
+
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	reasons := make([]string, len(e))
+	for i, err := range e {
+		reasons[i] = err.Field + ": " + err.Reason
+	}
+	return "ch912x: invalid configuration: " + strings.Join(reasons, "; ")
+}
+
+// Validator collects the ValidationErrors of a module against its capabilities,
+// the products of other packages validate their modules with it as the built-in ones do.
+type Validator struct {
+	caps *Capabilities
+	errs ValidationErrors
+}
+
+func NewValidator(caps *Capabilities) *Validator {
+	return &Validator{caps: caps}
+}
+
+// Check records the reason formatted with args against the field unless ok.
+func (v *Validator) Check(ok bool, field, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+}
+
+// Err returns the ValidationErrors found so far, nil when there is none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *Validator) Length(field, value string, max int) {
+	v.Check(len(value) <= max, field, "%d bytes exceeds the limit of %d bytes", len(value), max)
+}
+
+// Supported rejects the field set while the capability lacks it.
+func (v *Validator) Supported(ok, set bool, field string) {
+	v.Check(ok || !set, field, "not supported")
+}
+
+func (v *Validator) IPv4(field string, ip net.IP) {
+	v.Check(ip == nil || ip.To4() != nil, field, "%s is not an IPv4 address", ip)
+}
+
+func (v *Validator) Options(field string, options *ModuleOptions) {
+	if options == nil {
+		return
+	}
+	v.IPv4(field+".ip", options.IP)
+	v.IPv4(field+".mask", options.Mask)
+	v.IPv4(field+".gateway", options.Gateway)
+	v.Supported(v.caps.DHCP, options.UseDHCP, field+".use_dhcp")
+	v.Supported(v.caps.SerialNegotiate, options.SerialNegotiate, field+".serial_negotiate")
+	// the CH9126 takes EnabledMinorUART for the switch of its only UART
+	v.Supported(v.caps.UARTs > 1 || v.caps.UARTSwitch, options.EnabledMinorUART, field+".enabled_minor_uart")
+	if options.Mask.To4() == nil {
+		return
+	}
+	mask := net.IPMask(options.Mask.To4())
+	ones, bits := mask.Size()
+	if bits == 0 {
+		v.Check(false, field+".mask", "%s is not a contiguous subnet mask", options.Mask)
+		return
+	}
+	ip, gateway := options.IP.To4(), options.Gateway.To4()
+	if options.UseDHCP || ip == nil || gateway == nil || gateway.Equal(net.IPv4zero) {
+		return
+	}
+	v.Check(ip.Mask(mask).Equal(gateway.Mask(mask)), field+".gateway", "%s is outside the subnet %s/%d", gateway, ip.Mask(mask), ones)
+}
+
+func (v *Validator) UART(field string, uart *UARTService) {
+	if uart == nil {
+		return
+	}
+	caps := v.caps
+	v.Check(uart.Mode <= UDPClient, field+".mode", "unknown mode %d", uart.Mode)
+	v.IPv4(field+".client_ip", uart.ClientIP)
+	v.Supported(caps.UARTSwitch, uart.Enabled != nil, field+".enabled")
+	if caps.DomainLength > 0 {
+		v.Length(field+".client_domain", uart.ClientDomain, caps.DomainLength)
+	} else {
+		v.Supported(false, uart.ClientDomain != "", field+".client_domain")
+		v.Supported(false, uart.UseDomain, field+".use_domain")
+	}
+	v.Supported(caps.SocketOptions, uart.RandomClientPort, field+".random_client_port")
+	v.Supported(caps.SocketOptions, uart.CloseOnLost, field+".close_on_lost")
+	v.Supported(caps.SocketOptions, uart.ClearOnReconnect, field+".clear_on_reconnect")
+	if caps.MaxBaud == 0 {
+		v.Supported(false, uart.Baud != 0, field+".baud")
+		v.Supported(false, uart.DataBits != 0, field+".data_bits")
+		v.Supported(false, uart.StopBit != 0, field+".stop_bit")
+		v.Supported(false, uart.Parity != ParityNone, field+".parity")
+		v.Supported(false, uart.PacketSize != 0, field+".packet_size")
+		v.Supported(false, uart.PacketTimeout != 0, field+".packet_timeout")
+		return
+	}
+	v.Check(uart.Baud >= caps.MinBaud && uart.Baud <= caps.MaxBaud, field+".baud", "%d bps is out of range %d-%d bps", uart.Baud, caps.MinBaud, caps.MaxBaud)
+	v.Check(containsInt(caps.DataBits, int(uart.DataBits)), field+".data_bits", "%d is not one of %v", uart.DataBits, caps.DataBits)
+	v.Check(containsInt(caps.StopBits, int(uart.StopBit)), field+".stop_bit", "%d is not one of %v", uart.StopBit, caps.StopBits)
+	v.Check(containsParity(caps.Parities, uart.Parity), field+".parity", "unsupported parity %d", uart.Parity)
+	if caps.PacketSize > 0 {
+		v.Check(uart.PacketSize <= caps.PacketSize, field+".packet_size", "%d exceeds the limit of %d", uart.PacketSize, caps.PacketSize)
+	}
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsParity(values []UARTParity, value UARTParity) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CH9120) Validate() error {
+	v := NewValidator(p.Capabilities())
+	v.Length("module_name", p.ModuleName, v.caps.NameLength)
+	v.Options("module_options", p.ModuleOptions)
+	v.UART("uart_1", p.UART1)
+	return v.Err()
+}
+
+func (p *CH9121) Validate() error {
+	v := NewValidator(p.Capabilities())
+	v.Length("module_name", p.ModuleName, v.caps.NameLength)
+	v.Options("module_options", p.ModuleOptions)
+	v.UART("uart_1", p.UART1)
+	v.UART("uart_2", p.UART2)
+	return v.Err()
+}
+
+func (p *CH9126) Validate() error {
+	v := NewValidator(p.Capabilities())
+	v.Length("module_name", p.ModuleName, v.caps.NameLength)
+	v.Options("module_options", p.ModuleOptions)
+	v.UART("uart_1", p.UART1)
+	if options, uart := p.ModuleOptions, p.UART1; options != nil && uart != nil && uart.Enabled != nil {
+		v.Check(!options.EnabledMinorUART, "module_options.enabled_minor_uart", "deprecated, use uart_1.enabled")
+	}
+	if ntp := p.NTP; ntp != nil {
+		v.Check(!ntp.KeepAlive || p.KeepAlive == nil, "ntp.keep_alive", "deprecated, use keep_alive.enabled")
+		v.Supported(v.caps.NTP, ntp.Enabled, "ntp.enabled")
+		v.Check(ntp.Mode <= NTPClient, "ntp.mode", "unknown mode %d", ntp.Mode)
+		v.IPv4("ntp.client_ip", ntp.ClientIP)
+	}
+	if keepAlive := p.KeepAlive; keepAlive != nil {
+		v.Supported(v.caps.KeepAlive, keepAlive.Enabled, "keep_alive.enabled")
+	}
+	return v.Err()
+}
+
+func (p *NetModule) Validate() error {
+	v := NewValidator(p.Capabilities())
+	v.Length("module_name", p.ModuleName, v.caps.NameLength)
+	v.Options("module_options", p.ModuleOptions)
+	v.UART("uart_1", p.UART1)
+	if uart := p.UART1; uart != nil {
+		// the configuration has no packet size
+		v.Supported(false, uart.PacketSize != 0, "uart_1.packet_size")
+	}
+	return v.Err()
+}
@@ -0,0 +1,51 @@
+package ch912x_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/CursedHardware/ch912x"
+	"github.com/CursedHardware/ch912x/ch912xsim"
+)
+
+func TestValidateFields(t *testing.T) {
+	for _, test := range []struct {
+		module ch912x.Module
+		patch  string
+		fields []string
+	}{
+		{ch912xsim.NewCH9120(testMAC), `{}`, nil},
+		{ch912xsim.NewCH9121(testMAC), `{}`, nil},
+		{ch912xsim.NewCH9126(testMAC), `{}`, nil},
+		{ch912xsim.NewCH9121(testMAC), `{"module_name":"0123456789abcdefghijkl"}`, []string{"module_name"}},
+		{ch912xsim.NewCH9121(testMAC), `{"module_options":{"ip":"fe80::1","mask":"255.0.255.0"}}`, []string{"module_options.ip", "module_options.mask"}},
+		{ch912xsim.NewCH9121(testMAC), `{"module_options":{"gateway":"10.0.0.1"}}`, []string{"module_options.gateway"}},
+		{ch912xsim.NewCH9121(testMAC), `{"module_options":{"use_dhcp":true,"gateway":"10.0.0.1"}}`, nil},
+		{ch912xsim.NewCH9121(testMAC), `{"uart_1":{"mode":9},"uart_2":{"baud":100,"data_bits":9,"stop_bit":3}}`, []string{"uart_1.mode", "uart_2.baud", "uart_2.data_bits", "uart_2.stop_bit"}},
+		{ch912xsim.NewCH9121(testMAC), `{"uart_1":{"packet_size":2048,"enabled":true}}`, []string{"uart_1.enabled", "uart_1.packet_size"}},
+		{ch912xsim.NewCH9120(testMAC), `{"uart_1":{"client_domain":"0123456789.example.com"}}`, []string{"uart_1.client_domain"}},
+		{ch912xsim.NewCH9126(testMAC), `{"module_options":{"use_dhcp":true},"uart_1":{"close_on_lost":true,"use_domain":true}}`, []string{"module_options.use_dhcp", "uart_1.close_on_lost", "uart_1.use_domain"}},
+		{ch912xsim.NewCH9126(testMAC), `{"module_options":{"enabled_minor_uart":true},"ntp":{"mode":7,"client_ip":"fe80::1"}}`, []string{"module_options.enabled_minor_uart", "ntp.client_ip", "ntp.mode"}},
+		{ch912x.LookupProduct(ch912x.ProductNetModule).Request(ch912x.KindPushRequest, testMAC), `{"uart_1":{"baud":9600,"data_bits":8,"stop_bit":1,"packet_size":1024}}`, []string{"uart_1.packet_size"}},
+	} {
+		module, err := ch912x.MergePatch(test.module, []byte(test.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields []string
+		var errs ch912x.ValidationErrors
+		if err = module.Validate(); errors.As(err, &errs) {
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			sort.Strings(fields)
+		} else if err != nil {
+			t.Fatalf("%s %s: got %v", module.Product(), test.patch, err)
+		}
+		if !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("%s %s: got %v, want %v", module.Product(), test.patch, fields, test.fields)
+		}
+	}
+}